@@ -0,0 +1,115 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// the buckets are logarithmic between histMin and histMax milliseconds, the same as sysbench --histogram.
+const (
+	histSize = 1024
+	histMin  = 0.001
+	histMax  = 100000.0
+)
+
+var histMult = float64(histSize) / (math.Log(histMax) - math.Log(histMin))
+
+// Histogram is the latencies of the transactions in milliseconds.
+type Histogram struct {
+	counts [histSize]int64
+	count  int64
+	sum    float64
+	max    float64
+}
+
+func bucket(ms float64) int {
+	if ms <= histMin {
+		return 0
+	}
+	i := int((math.Log(ms) - math.Log(histMin)) * histMult)
+	if i >= histSize {
+		return histSize - 1
+	}
+	return i
+}
+
+// bucketValue is the upper bound of the bucket.
+func bucketValue(i int) float64 {
+	return math.Exp(float64(i+1)/histMult + math.Log(histMin))
+}
+
+func (h *Histogram) Add(ms float64) {
+	h.counts[bucket(ms)]++
+	h.count++
+	h.sum += ms
+	if ms > h.max {
+		h.max = ms
+	}
+}
+
+func (h *Histogram) Merge(o *Histogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.sum += o.sum
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+func (h *Histogram) Avg() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+func (h *Histogram) Max() float64 {
+	return h.max
+}
+
+// Percentile is the upper bound of the bucket where the p percent of the latencies are below, e.g. 95.
+func (h *Histogram) Percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	n := int64(math.Ceil(float64(h.count) * p / 100))
+	var cnt int64
+	for i, c := range h.counts {
+		cnt += c
+		if cnt >= n {
+			return math.Min(bucketValue(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// Write prints the non-empty buckets like sysbench --histogram.
+func (h *Histogram) Write(w io.Writer) {
+	const width = 40
+	var most int64
+	for _, c := range h.counts {
+		if c > most {
+			most = c
+		}
+	}
+	fmt.Fprintf(w, "Latency histogram (values are in milliseconds)\n")
+	fmt.Fprintf(w, "%12s  %s %s\n", "value", strings.Repeat("-", 13)+" distribution "+strings.Repeat("-", 13), "count")
+	if most == 0 {
+		return
+	}
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		stars := int(math.Ceil(float64(c) * width / float64(most)))
+		fmt.Fprintf(w, "%12.3f |%-40s %d\n", bucketValue(i), strings.Repeat("*", stars), c)
+	}
+}
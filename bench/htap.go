@@ -0,0 +1,403 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"pictorial/log"
+	"pictorial/mysql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	htapOrders    = "htap_orders"
+	htapFreshness = "htap_freshness"
+	// replicaInterval is the interval of polling information_schema.tiflash_replica.
+	replicaInterval = 5 * time.Second
+	// freshnessInterval is the interval between the writes of the freshness row.
+	freshnessInterval = time.Second
+	// freshnessPoll is the interval of reading the freshness row from tiflash until the write is visible.
+	freshnessPoll    = 10 * time.Millisecond
+	freshnessTimeout = time.Minute
+)
+
+// the kinds of the htap meter, the tps and the latency of the samples are of the tp writes.
+const (
+	htapTP = iota
+	htapAP
+	htapLag
+)
+
+var htapKinds = []string{"tp", "ap", "freshness"}
+
+// htapQueries are the analytical queries run in turn through tiflash mpp.
+var htapQueries = []string{
+	"SELECT status, COUNT(*), SUM(amount), AVG(amount) FROM %s GROUP BY status",
+	"SELECT k %% 100 AS b, COUNT(*), MAX(amount) FROM %s GROUP BY b ORDER BY 2 DESC LIMIT 10",
+	"SELECT COUNT(DISTINCT k), SUM(amount) FROM %s WHERE created_at > NOW() - INTERVAL 1 MINUTE",
+}
+
+// Htap writes to tikv and queries tiflash at the same time, the cmd is prepare, run, check or cleanup.
+// Prepare sets the tiflash replicas and waits until they are available. Run measures the latency of the
+// mpp queries and the freshness lag, which is the time from a commit until it is read from tiflash.
+type Htap struct {
+	Mysql    mysql.MySQL
+	DB       string
+	Replicas int
+	// Rows are inserted by prepare, the tp writes update them or insert new ones.
+	Rows      int
+	Threads   int
+	APThreads int
+	Cmd       string
+	// Duration is the time of run, it runs until the context is done if 0.
+	Duration time.Duration
+	// ReplicaTimeout is how long prepare waits for the tiflash replicas.
+	ReplicaTimeout time.Duration
+}
+
+func (h *Htap) String() string {
+	s := fmt.Sprintf("htap %s --db=%s --replicas=%d --rows=%d --threads=%d --ap-threads=%d",
+		h.cmd(), h.DB, h.replicas(), h.rows(), h.threads(), h.apThreads())
+	if h.Duration != 0 {
+		s += fmt.Sprintf(" --time=%s", h.Duration)
+	}
+	return s
+}
+
+func (h *Htap) cmd() string {
+	if h.Cmd == "" {
+		return CmdRun
+	}
+	return h.Cmd
+}
+
+func (h *Htap) replicas() int {
+	if h.Replicas <= 0 {
+		return 1
+	}
+	return h.Replicas
+}
+
+func (h *Htap) rows() int {
+	if h.Rows <= 0 {
+		return 1
+	}
+	return h.Rows
+}
+
+func (h *Htap) threads() int {
+	if h.Threads <= 0 {
+		return 1
+	}
+	return h.Threads
+}
+
+func (h *Htap) apThreads() int {
+	if h.APThreads <= 0 {
+		return 1
+	}
+	return h.APThreads
+}
+
+func (h *Htap) table(name string) string {
+	return h.DB + "." + name
+}
+
+func (h *Htap) session() *mysql.Session {
+	s, _ := h.Mysql.NewSessionWithTimeout(h.Mysql.User, h.Mysql.Password, oltpTimeout)
+	return s
+}
+
+// Exec runs the cmd, run prepares the tables first if they are not ready.
+func (h *Htap) Exec(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	switch h.cmd() {
+	case CmdPrepare:
+		return nil, h.Prepare(ctx)
+	case CmdCheck:
+		return nil, h.Check(ctx, w)
+	case CmdCleanup:
+		return nil, h.Cleanup()
+	case CmdRun:
+		if err := h.Prepare(ctx); err != nil {
+			return nil, err
+		}
+		return h.Run(ctx, w, report)
+	}
+	return nil, fmt.Errorf("%s is not supported by htap", h.cmd())
+}
+
+// Cleanup drops the tables.
+func (h *Htap) Cleanup() error {
+	s := h.session()
+	defer s.Close()
+	for _, t := range []string{htapOrders, htapFreshness} {
+		if r := s.Execute("DROP TABLE IF EXISTS " + h.table(t)); r.Failed() {
+			return fmt.Errorf("[htap] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// Prepare creates the tables, fills the empty orders with Rows rows, sets the tiflash replicas
+// and waits until AVAILABLE of information_schema.tiflash_replica is 1 for both tables.
+func (h *Htap) Prepare(ctx context.Context) error {
+	s := h.session()
+	defer s.Close()
+	for _, stmt := range []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", h.DB),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGINT NOT NULL AUTO_INCREMENT, k INT NOT NULL, "+
+			"amount DECIMAL(12,2) NOT NULL, status TINYINT NOT NULL, created_at DATETIME(6) NOT NULL, PRIMARY KEY (id))", h.table(htapOrders)),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INT NOT NULL, seq BIGINT NOT NULL, ts DATETIME(6) NOT NULL, PRIMARY KEY (id))",
+			h.table(htapFreshness)),
+		fmt.Sprintf("INSERT IGNORE INTO %s VALUES (1, 0, NOW(6))", h.table(htapFreshness)),
+	} {
+		if r := s.Execute(stmt); r.Failed() {
+			return fmt.Errorf("[htap] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	r := s.Execute(fmt.Sprintf("SELECT id FROM %s LIMIT 1", h.table(htapOrders)))
+	if r.Failed() {
+		return fmt.Errorf("[htap] %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	if len(r.Rows) == 0 {
+		log.Logger.Infof("[htap] inserting %d rows into %s", h.rows(), h.table(htapOrders))
+		rnd := newRand(0)
+		for from := 1; from <= h.rows(); from += prepareBatch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			values := make([]string, 0, prepareBatch)
+			for id := from; id < from+prepareBatch && id <= h.rows(); id++ {
+				values = append(values, fmt.Sprintf("(%d,%s)", id, h.order(rnd)))
+			}
+			if r := s.Execute(fmt.Sprintf("INSERT INTO %s (id, k, amount, status, created_at) VALUES %s",
+				h.table(htapOrders), strings.Join(values, ","))); r.Failed() {
+				return fmt.Errorf("[htap] insert into %s failed, %d: %s", h.table(htapOrders), r.ErrCode, r.ErrMsg)
+			}
+		}
+	}
+	for _, t := range []string{htapOrders, htapFreshness} {
+		if r := s.Execute(fmt.Sprintf("ALTER TABLE %s SET TIFLASH REPLICA %d", h.table(t), h.replicas())); r.Failed() {
+			return fmt.Errorf("[htap] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	return h.waitReplicas(ctx, s)
+}
+
+func (h *Htap) waitReplicas(ctx context.Context, s *mysql.Session) error {
+	d := h.ReplicaTimeout
+	if d == 0 {
+		d = 10 * time.Minute
+	}
+	timeout := time.After(d)
+	ticker := time.NewTicker(replicaInterval)
+	defer ticker.Stop()
+	var progress []string
+	for {
+		r := s.Execute(fmt.Sprintf("SELECT TABLE_NAME, AVAILABLE, PROGRESS FROM information_schema.tiflash_replica "+
+			"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME IN ('%s', '%s')", h.DB, htapOrders, htapFreshness))
+		if r.Failed() {
+			return fmt.Errorf("[htap] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+		progress = progress[:0]
+		available := 0
+		for _, row := range r.Rows {
+			if row[1] == "1" {
+				available++
+			}
+			progress = append(progress, fmt.Sprintf("%s available %s progress %s", row[0], row[1], row[2]))
+		}
+		if available == 2 {
+			log.Logger.Infof("[htap] the tiflash replicas are available.")
+			return nil
+		}
+		log.Logger.Infof("[htap] waiting for the tiflash replicas: %s", strings.Join(progress, ", "))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("[htap] the tiflash replicas are not available in %s: %s", d, strings.Join(progress, ", "))
+		case <-ticker.C:
+		}
+	}
+}
+
+// order is the values of an order except the id.
+func (h *Htap) order(r *rand.Rand) string {
+	return fmt.Sprintf("%d,%.2f,%d,NOW(6)", r.Intn(h.rows())+1, float64(r.Intn(100000))/100, r.Intn(5))
+}
+
+// Run writes with Threads, queries with APThreads and measures the freshness on one more thread,
+// until the duration or the context is done.
+func (h *Htap) Run(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	m := newMeter(htapTP, htapKinds...)
+	drive(ctx, h.Duration, h.threads()+h.apThreads()+1, m, w, report, func(ctx context.Context, n int) {
+		r := newRand(int64(n))
+		s := h.session()
+		defer s.Close()
+		switch {
+		case n < h.threads():
+			h.tp(ctx, s, r, m)
+		case n < h.threads()+h.apThreads():
+			h.ap(ctx, s, n, m)
+		default:
+			h.freshness(ctx, s, m)
+		}
+	})
+	sum, _ := m.summary()
+	sum.write(w, "htap")
+	for _, d := range sum.Details {
+		if d.Name == htapKinds[htapLag] {
+			fmt.Fprintf(w, "freshness lag: avg %.2fms p99 %.2fms max %.2fms\n", d.Avg, d.P99, d.Max)
+		}
+	}
+	return sum, nil
+}
+
+// tp updates an order or inserts a new one.
+func (h *Htap) tp(ctx context.Context, s *mysql.Session, r *rand.Rand, m *meter) {
+	for ctx.Err() == nil {
+		var sql string
+		if r.Intn(2) == 0 {
+			sql = fmt.Sprintf("INSERT INTO %s (k, amount, status, created_at) VALUES (%s)", h.table(htapOrders), h.order(r))
+		} else {
+			sql = fmt.Sprintf("UPDATE %s SET amount = amount + %.2f, status = (status + 1) %% 5 WHERE id = %d",
+				h.table(htapOrders), float64(r.Intn(10000))/100, r.Intn(h.rows())+1)
+		}
+		start := time.Now()
+		res := s.Execute(sql)
+		if ctx.Err() != nil {
+			return
+		}
+		m.add(htapTP, time.Since(start), 1, resultErr(res))
+		if res.ConnectionLost() {
+			time.Sleep(errorBackoff)
+		}
+	}
+}
+
+// tiflash makes the session read from tiflash only, so a query fails instead of falling back to tikv
+// while tiflash is down. It is set again after the connection is lost.
+func tiflash(s *mysql.Session, mpp bool) error {
+	stmts := []string{"SET @@session.tidb_isolation_read_engines = 'tiflash'"}
+	if mpp {
+		stmts = append(stmts, "SET @@session.tidb_enforce_mpp = 1")
+	}
+	for _, stmt := range stmts {
+		if err := resultErr(s.Execute(stmt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Htap) ap(ctx context.Context, s *mysql.Session, n int, m *meter) {
+	ready := false
+	for i := n; ctx.Err() == nil; i++ {
+		start := time.Now()
+		var err error
+		queries := 0
+		if !ready {
+			err = tiflash(s, true)
+			ready = err == nil
+		}
+		var res *mysql.StatementResult
+		if err == nil {
+			res = s.Execute(fmt.Sprintf(htapQueries[i%len(htapQueries)], h.table(htapOrders)))
+			err = resultErr(res)
+			queries = 1
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		m.add(htapAP, time.Since(start), queries, err)
+		if err != nil {
+			// the queries fail at once while tiflash is down
+			ready = ready && !res.ConnectionLost()
+			time.Sleep(errorBackoff)
+		}
+	}
+}
+
+// freshness updates the seq of the freshness row and reads it from tiflash until the new seq is visible.
+func (h *Htap) freshness(ctx context.Context, s *mysql.Session, m *meter) {
+	tp := h.session()
+	defer tp.Close()
+	ready := false
+	var seq int64
+	for ctx.Err() == nil {
+		seq++
+		queries := 1
+		err := resultErr(tp.Execute(fmt.Sprintf("UPDATE %s SET seq = %d, ts = NOW(6) WHERE id = 1", h.table(htapFreshness), seq)))
+		start := time.Now()
+		for err == nil && ctx.Err() == nil {
+			if !ready {
+				if err = tiflash(s, false); err != nil {
+					break
+				}
+				ready = true
+			}
+			queries++
+			res := s.Execute(fmt.Sprintf("SELECT seq FROM %s WHERE id = 1", h.table(htapFreshness)))
+			if err = resultErr(res); err != nil {
+				ready = !res.ConnectionLost()
+				break
+			}
+			if len(res.Rows) != 0 {
+				if v, _ := strconv.ParseInt(res.Rows[0][0], 10, 64); v >= seq {
+					break
+				}
+			}
+			if time.Since(start) > freshnessTimeout {
+				err = fmt.Errorf("seq %d is not visible in tiflash after %s", seq, freshnessTimeout)
+				break
+			}
+			time.Sleep(freshnessPoll)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		m.add(htapLag, time.Since(start), queries, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(freshnessInterval):
+		}
+	}
+}
+
+// Check compares the count and the sum of the orders read from tikv and tiflash in one statement,
+// so both of them are of the same snapshot.
+func (h *Htap) Check(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := h.session()
+	defer s.Close()
+	t := h.table(htapOrders)
+	r := s.Execute(fmt.Sprintf("SELECT a.c, a.s, b.c, b.s FROM "+
+		"(SELECT /*+ READ_FROM_STORAGE(TIKV[o1]) */ COUNT(*) c, SUM(amount) s FROM %s o1) a, "+
+		"(SELECT /*+ READ_FROM_STORAGE(TIFLASH[o2]) */ COUNT(*) c, SUM(amount) s FROM %s o2) b", t, t))
+	if err := resultErr(r); err != nil {
+		fmt.Fprintf(w, "[htap] check: error %s\n", err.Error())
+		return fmt.Errorf("htap check failed, %s", err.Error())
+	}
+	if len(r.Rows) == 0 {
+		return fmt.Errorf("htap check failed, no result")
+	}
+	row := r.Rows[0]
+	fmt.Fprintf(w, "[htap] check: tikv count %s sum %s, tiflash count %s sum %s\n", row[0], row[1], row[2], row[3])
+	if row[0] != row[2] || row[1] != row[3] {
+		return fmt.Errorf("htap check failed, tikv count %s sum %s, tiflash count %s sum %s", row[0], row[1], row[2], row[3])
+	}
+	return nil
+}
+
+func resultErr(r *mysql.StatementResult) error {
+	if r.Failed() {
+		return fmt.Errorf("%d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
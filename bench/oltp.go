@@ -0,0 +1,301 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"pictorial/log"
+	"pictorial/mysql"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultInterval is the report interval of the runners, the same as --report-interval of the sysbench cmd.
+	defaultInterval = 10 * time.Second
+	prepareBatch    = 1000
+	rangeSize       = 100
+	pointSelects    = 10
+	// oltpTimeout bounds each statement so a stopped workload does not hang on a dead connection.
+	oltpTimeout = 30 * time.Second
+	// errorBackoff is the wait after a transaction lost its connection.
+	errorBackoff = 100 * time.Millisecond
+)
+
+// Oltp runs the sysbench workloads in process through the mysql protocol, so no sysbench binary is needed.
+// The failed transactions are counted as errors and the workload goes on, like sysbench --mysql-ignore-errors=all.
+type Oltp struct {
+	Test      TestTp
+	Db        string
+	TableSize int
+	Tables    int
+	Threads   int
+	Cmd       string
+	Mysql     mysql.MySQL
+	// Duration is the time of run, it runs until the context is done if 0.
+	Duration time.Duration
+}
+
+func (o *Oltp) String() string {
+	s := fmt.Sprintf("%s %s --mysql-db=%s --table-size=%d --tables=%d --threads=%d",
+		GetSysbenchTpValue(o.Test), o.cmd(), o.Db, o.TableSize, o.Tables, o.Threads)
+	if o.Duration != 0 {
+		s += fmt.Sprintf(" --time=%d", int(o.Duration.Seconds()))
+	}
+	return s
+}
+
+func (o *Oltp) cmd() string {
+	if o.Cmd == "" {
+		return CmdRun
+	}
+	return o.Cmd
+}
+
+func (o *Oltp) table(n int) string {
+	return fmt.Sprintf("%s.sbtest%d", o.Db, n)
+}
+
+func (o *Oltp) session() *mysql.Session {
+	// the session reconnects on the next statement if the connecting failed
+	s, _ := o.Mysql.NewSessionWithTimeout(o.Mysql.User, o.Mysql.Password, oltpTimeout)
+	return s
+}
+
+// Exec runs the cmd, run prepares the tables first if they are empty.
+func (o *Oltp) Exec(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	switch o.cmd() {
+	case CmdPrepare:
+		return nil, o.Prepare(ctx)
+	case CmdCleanup:
+		return nil, o.Cleanup()
+	case CmdRun:
+		if err := o.Prepare(ctx); err != nil {
+			return nil, err
+		}
+		return o.Run(ctx, w, report)
+	}
+	return nil, fmt.Errorf("%s is not supported by %s", o.cmd(), GetSysbenchTpValue(o.Test))
+}
+
+// Cleanup drops the tables.
+func (o *Oltp) Cleanup() error {
+	s := o.session()
+	defer s.Close()
+	for i := 1; i <= o.tables(); i++ {
+		if r := s.Execute(fmt.Sprintf("DROP TABLE IF EXISTS %s", o.table(i))); r.Failed() {
+			return fmt.Errorf("[%s] %d: %s", GetSysbenchTpValue(o.Test), r.ErrCode, r.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// Prepare creates the tables of sysbench and fills the empty ones with TableSize rows, the filled ones are kept.
+func (o *Oltp) Prepare(ctx context.Context) error {
+	ov := GetSysbenchTpValue(o.Test)
+	s := o.session()
+	defer s.Close()
+	if r := s.Execute(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", o.Db)); r.Failed() {
+		return fmt.Errorf("[%s] %d: %s", ov, r.ErrCode, r.ErrMsg)
+	}
+	type batch struct {
+		table    string
+		from, to int
+	}
+	var batches []batch
+	for i := 1; i <= o.tables(); i++ {
+		t := o.table(i)
+		if r := s.Execute(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+			"id INT NOT NULL AUTO_INCREMENT, k INT NOT NULL DEFAULT '0', "+
+			"c CHAR(120) NOT NULL DEFAULT '', pad CHAR(60) NOT NULL DEFAULT '', "+
+			"PRIMARY KEY (id), KEY k_1 (k))", t)); r.Failed() {
+			return fmt.Errorf("[%s] %d: %s", ov, r.ErrCode, r.ErrMsg)
+		}
+		r := s.Execute(fmt.Sprintf("SELECT id FROM %s LIMIT 1", t))
+		if r.Failed() {
+			return fmt.Errorf("[%s] %d: %s", ov, r.ErrCode, r.ErrMsg)
+		}
+		if len(r.Rows) != 0 {
+			log.Logger.Infof("[%s] %s is not empty, skip inserting.", ov, t)
+			continue
+		}
+		log.Logger.Infof("[%s] inserting %d rows into %s", ov, o.TableSize, t)
+		for from := 1; from <= o.TableSize; from += prepareBatch {
+			to := from + prepareBatch - 1
+			if to > o.TableSize {
+				to = o.TableSize
+			}
+			batches = append(batches, batch{t, from, to})
+		}
+	}
+
+	batchC := make(chan batch)
+	errC := make(chan error, o.threads())
+	var wg sync.WaitGroup
+	for i := 0; i < o.threads(); i++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			s := o.session()
+			defer s.Close()
+			for b := range batchC {
+				values := make([]string, 0, b.to-b.from+1)
+				for id := b.from; id <= b.to; id++ {
+					values = append(values, fmt.Sprintf("(%d,%d,'%s','%s')", id, r.Intn(o.TableSize)+1, randC(r), randPad(r)))
+				}
+				res := s.Execute(fmt.Sprintf("INSERT INTO %s (id, k, c, pad) VALUES %s", b.table, strings.Join(values, ",")))
+				if res.Failed() {
+					errC <- fmt.Errorf("[%s] insert into %s failed, %d: %s", ov, b.table, res.ErrCode, res.ErrMsg)
+					return
+				}
+			}
+		}(newRand(int64(i)))
+	}
+	var err error
+loop:
+	for _, b := range batches {
+		select {
+		case batchC <- b:
+		case err = <-errC:
+			break loop
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		}
+	}
+	close(batchC)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errC:
+		default:
+		}
+	}
+	return err
+}
+
+// Run runs the transactions until the duration or the context is done, a sample of every interval is reported
+// and written to w, the summary and the latency histogram are written at the end.
+func (o *Oltp) Run(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	ov := GetSysbenchTpValue(o.Test)
+	m := newMeter(-1, ov)
+	drive(ctx, o.Duration, o.threads(), m, w, report, func(ctx context.Context, n int) {
+		r := newRand(int64(n))
+		s := o.session()
+		defer s.Close()
+		for ctx.Err() == nil {
+			start := time.Now()
+			queries, lost, err := o.transaction(s, r)
+			if ctx.Err() != nil {
+				return
+			}
+			m.add(0, time.Since(start), queries, err)
+			if lost {
+				time.Sleep(errorBackoff)
+			}
+		}
+	})
+	sum, hist := m.summary()
+	sum.write(w, ov)
+	hist.Write(w)
+	return sum, nil
+}
+
+// transaction runs one transaction of the test on a random table, lost is true if the connection is lost.
+func (o *Oltp) transaction(s *mysql.Session, r *rand.Rand) (int, bool, error) {
+	t := o.table(r.Intn(o.tables()) + 1)
+	id := func() int {
+		return r.Intn(o.size()) + 1
+	}
+	var sqls []string
+	switch o.Test {
+	case OltpPointSelect:
+		sqls = append(sqls, fmt.Sprintf("SELECT c FROM %s WHERE id=%d", t, id()))
+	case OltpInsert:
+		sqls = append(sqls, fmt.Sprintf("INSERT INTO %s (k, c, pad) VALUES (%d,'%s','%s')", t, id(), randC(r), randPad(r)))
+	case OltpReadWrite:
+		sqls = append(sqls, "BEGIN")
+		for i := 0; i < pointSelects; i++ {
+			sqls = append(sqls, fmt.Sprintf("SELECT c FROM %s WHERE id=%d", t, id()))
+		}
+		for _, q := range []string{
+			"SELECT c FROM %s WHERE id BETWEEN %d AND %d",
+			"SELECT SUM(k) FROM %s WHERE id BETWEEN %d AND %d",
+			"SELECT c FROM %s WHERE id BETWEEN %d AND %d ORDER BY c",
+			"SELECT DISTINCT c FROM %s WHERE id BETWEEN %d AND %d ORDER BY c",
+		} {
+			from := id()
+			sqls = append(sqls, fmt.Sprintf(q, t, from, from+rangeSize-1))
+		}
+		sqls = append(sqls,
+			fmt.Sprintf("UPDATE %s SET k=k+1 WHERE id=%d", t, id()),
+			fmt.Sprintf("UPDATE %s SET c='%s' WHERE id=%d", t, randC(r), id()))
+		del := id()
+		sqls = append(sqls,
+			fmt.Sprintf("DELETE FROM %s WHERE id=%d", t, del),
+			fmt.Sprintf("INSERT INTO %s (id, k, c, pad) VALUES (%d,%d,'%s','%s')", t, del, id(), randC(r), randPad(r)),
+			"COMMIT")
+	default:
+		return 0, false, fmt.Errorf("unknown test %d", o.Test)
+	}
+	for i, sql := range sqls {
+		res := s.Execute(sql)
+		if !res.Failed() {
+			continue
+		}
+		if sqls[0] == "BEGIN" && !res.ConnectionLost() {
+			s.Execute("ROLLBACK")
+		}
+		return i + 1, res.ConnectionLost(), fmt.Errorf("%d: %s", res.ErrCode, res.ErrMsg)
+	}
+	return len(sqls), false, nil
+}
+
+func (o *Oltp) threads() int {
+	if o.Threads <= 0 {
+		return 1
+	}
+	return o.Threads
+}
+
+func (o *Oltp) tables() int {
+	if o.Tables <= 0 {
+		return 1
+	}
+	return o.Tables
+}
+
+func (o *Oltp) size() int {
+	if o.TableSize <= 0 {
+		return 1
+	}
+	return o.TableSize
+}
+
+func newRand(n int64) *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano() + n))
+}
+
+// randC and randPad are the values of the c and pad columns of sysbench, groups of 11 digits joined by '-'.
+func randC(r *rand.Rand) string {
+	return randGroups(r, 10)
+}
+
+func randPad(r *rand.Rand) string {
+	return randGroups(r, 5)
+}
+
+func randGroups(r *rand.Rand, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			sb.WriteByte('-')
+		}
+		for j := 0; j < 11; j++ {
+			sb.WriteByte(byte('0' + r.Intn(10)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,231 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// the commands of the runners, the same as sysbench and go-tpc.
+const (
+	CmdPrepare = "prepare"
+	CmdRun     = "run"
+	CmdCheck   = "check"
+	CmdCleanup = "cleanup"
+)
+
+// Runner is a workload running in tipoc instead of a shell command, Exec runs its cmd.
+// The samples of the run are reported directly and the lines written to w are the log of the workload.
+type Runner interface {
+	fmt.Stringer
+	Exec(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error)
+}
+
+// Checker verifies the data written by the workload, e.g. the consistency conditions of tpcc.
+type Checker interface {
+	Check(ctx context.Context, w io.Writer) error
+}
+
+// Summary is the result of a run, the latencies are milliseconds.
+type Summary struct {
+	Transactions int64   `json:"transactions"`
+	Queries      int64   `json:"queries"`
+	Errors       int64   `json:"errors"`
+	TPS          float64 `json:"tps"`
+	// TpmC is the new orders per minute of tpcc.
+	TpmC float64 `json:"tpmc,omitempty"`
+	Avg  float64 `json:"avg_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+	// Details are the transactions of tpcc or the queries of tpch.
+	Details []Detail `json:"details,omitempty"`
+}
+
+// Detail is the result of one kind of the transactions or queries.
+type Detail struct {
+	Name   string  `json:"name"`
+	Count  int64   `json:"count"`
+	Errors int64   `json:"errors"`
+	Avg    float64 `json:"avg_ms"`
+	P95    float64 `json:"p95_ms"`
+	P99    float64 `json:"p99_ms"`
+	Max    float64 `json:"max_ms"`
+}
+
+func (s *Summary) latency(h *Histogram) {
+	s.Avg = h.Avg()
+	s.P95 = h.Percentile(95)
+	s.P99 = h.Percentile(99)
+	s.Max = h.Max()
+}
+
+func (s *Summary) addDetail(name string, errs int64, h *Histogram) {
+	s.Details = append(s.Details, Detail{
+		Name:   name,
+		Count:  h.Count(),
+		Errors: errs,
+		Avg:    h.Avg(),
+		P95:    h.Percentile(95),
+		P99:    h.Percentile(99),
+		Max:    h.Max(),
+	})
+}
+
+func (s *Summary) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s: transactions %d (%.2f per sec.), queries %d, errors %d, latency avg %.2fms p95 %.2fms p99 %.2fms max %.2fms\n",
+		name, s.Transactions, s.TPS, s.Queries, s.Errors, s.Avg, s.P95, s.P99, s.Max)
+	if s.TpmC != 0 {
+		fmt.Fprintf(w, "tpmC: %.1f\n", s.TpmC)
+	}
+	for _, d := range s.Details {
+		fmt.Fprintf(w, "%-14s count %d, errors %d, latency avg %.2fms p95 %.2fms p99 %.2fms max %.2fms\n",
+			d.Name, d.Count, d.Errors, d.Avg, d.P95, d.P99, d.Max)
+	}
+}
+
+type counters struct {
+	txns    int64
+	queries int64
+	errs    int64
+	hist    Histogram
+}
+
+// meter counts the transactions of each kind for the current report interval and the whole run.
+type meter struct {
+	mu    sync.Mutex
+	names []string
+	cur   []counters
+	total []counters
+	// main is the kind of the tps and the latency of the samples, all of the kinds if it is negative.
+	main  int
+	begin time.Time
+	last  time.Time
+}
+
+func newMeter(main int, names ...string) *meter {
+	now := time.Now()
+	return &meter{
+		names: names,
+		cur:   make([]counters, len(names)),
+		total: make([]counters, len(names)),
+		main:  main,
+		begin: now,
+		last:  now,
+	}
+}
+
+func (m *meter) add(kind int, d time.Duration, queries int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := &m.cur[kind]
+	c.queries += int64(queries)
+	if err != nil {
+		c.errs++
+		return
+	}
+	c.txns++
+	c.hist.Add(float64(d) / float64(time.Millisecond))
+}
+
+// sample takes the counters of the interval into the total, false if the interval is empty.
+func (m *meter) sample(now time.Time) (Sample, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secs := now.Sub(m.last).Seconds()
+	m.last = now
+	var txns, queries, errs int64
+	var hist Histogram
+	for i := range m.cur {
+		c := &m.cur[i]
+		t := &m.total[i]
+		t.txns += c.txns
+		t.queries += c.queries
+		t.errs += c.errs
+		t.hist.Merge(&c.hist)
+		queries += c.queries
+		errs += c.errs
+		if m.main < 0 || m.main == i {
+			txns += c.txns
+			hist.Merge(&c.hist)
+		}
+		m.cur[i] = counters{}
+	}
+	if secs <= 0 {
+		return Sample{}, false
+	}
+	return Sample{
+		Time:       now,
+		TPS:        float64(txns) / secs,
+		QPS:        float64(queries) / secs,
+		Latency:    hist.Percentile(95),
+		Percentile: "95",
+		Errors:     float64(errs) / secs,
+	}, true
+}
+
+// summary is the whole run, with a detail for each kind if there are more than one.
+func (m *meter) summary() (*Summary, *Histogram) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sum := &Summary{}
+	var all Histogram
+	for i := range m.total {
+		t := &m.total[i]
+		sum.Transactions += t.txns
+		sum.Queries += t.queries
+		sum.Errors += t.errs
+		all.Merge(&t.hist)
+		if len(m.total) > 1 {
+			sum.addDetail(m.names[i], t.errs, &t.hist)
+		}
+	}
+	if elapsed := m.last.Sub(m.begin).Seconds(); elapsed > 0 {
+		sum.TPS = float64(sum.Transactions) / elapsed
+	}
+	sum.latency(&all)
+	return sum, &all
+}
+
+// drive runs the worker on each thread until the duration or the context is done,
+// a sample of the meter is written to w and reported every interval.
+func drive(ctx context.Context, d time.Duration, threads int, m *meter, w io.Writer, report func(Sample), worker func(ctx context.Context, thread int)) {
+	if d != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			worker(ctx, n)
+		}(i)
+	}
+	emit := func(now time.Time) {
+		s, ok := m.sample(now)
+		if !ok {
+			return
+		}
+		// the line is not in the format of sysbench, so it is not parsed again from the log
+		fmt.Fprintf(w, "[%ds] thds: %d %s\n", int(now.Sub(m.begin).Seconds()), threads, s)
+		if report != nil {
+			report(s)
+		}
+	}
+	ticker := time.NewTicker(defaultInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case now := <-ticker.C:
+			emit(now)
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	wg.Wait()
+	emit(time.Now())
+}
@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample is the throughput of one report interval of the load.
+type Sample struct {
+	// Name is the workload of the load, empty for the unnamed one.
+	Name string
+	Time time.Time
+	TPS  float64
+	QPS  float64
+	// Latency is the milliseconds of the Percentile, e.g. 95.
+	Latency    float64
+	Percentile string
+	// Errors is the errors per second.
+	Errors float64
+}
+
+func (s Sample) String() string {
+	if s.Name != "" {
+		return fmt.Sprintf("[%s] tps: %.2f qps: %.2f p%s: %.2fms err/s: %.2f", s.Name, s.TPS, s.QPS, s.Percentile, s.Latency, s.Errors)
+	}
+	return fmt.Sprintf("tps: %.2f qps: %.2f p%s: %.2fms err/s: %.2f", s.TPS, s.QPS, s.Percentile, s.Latency, s.Errors)
+}
+
+var (
+	// [ 10s ] thds: 8 tps: 1234.56 qps: 24691.20 (r/w/o: 17283.84/4938.24/2469.12) lat (ms,95%): 12.34 err/s: 0.00 reconn/s: 0.00
+	sysbenchLine = regexp.MustCompile(`^\[ *\d+s \] thds: \d+ tps: ([\d.]+) qps: ([\d.]+) .*lat \(ms,(\d+)%\): ([\d.]+) err/s: ([\d.]+)`)
+	// [Current] NEW_ORDER - Takes(s): 9.9, Count: 1090, TPM: 6597.6, Sum(ms): 18240.9, Avg(ms): 16.7, 50th(ms): 15.7, 90th(ms): 22.0, 95th(ms): 25.2, ...
+	tpccLine = regexp.MustCompile(`^\[Current\] (\w+) - Takes\(s\): ([\d.]+), Count: (\d+), TPM: ([\d.]+)`)
+	tpccP95  = regexp.MustCompile(`95th\(ms\): ([\d.]+)`)
+)
+
+const tpccNewOrder = "NEW_ORDER"
+
+// Parser reads the report lines of sysbench --report-interval and tiup bench tpcc.
+// A tpcc interval prints one line for each transaction, the sample is taken at NEW_ORDER,
+// so the failed transactions printed after it are counted into the next sample.
+type Parser struct {
+	errs float64
+}
+
+// Parse returns the sample of the line at the time, false if it is not a report line.
+func (p *Parser) Parse(line string, t time.Time) (Sample, bool) {
+	line = strings.TrimSpace(line)
+	if m := sysbenchLine.FindStringSubmatch(line); m != nil {
+		return Sample{
+			Time:       t,
+			TPS:        parseFloat(m[1]),
+			QPS:        parseFloat(m[2]),
+			Percentile: m[3],
+			Latency:    parseFloat(m[4]),
+			Errors:     parseFloat(m[5]),
+		}, true
+	}
+	m := tpccLine.FindStringSubmatch(line)
+	if m == nil {
+		return Sample{}, false
+	}
+	takes := parseFloat(m[2])
+	if strings.HasSuffix(m[1], "_ERR") {
+		if takes != 0 {
+			p.errs += parseFloat(m[3]) / takes
+		}
+		return Sample{}, false
+	}
+	if m[1] != tpccNewOrder {
+		return Sample{}, false
+	}
+	s := Sample{
+		Time:       t,
+		TPS:        parseFloat(m[4]) / 60,
+		Percentile: "95",
+		Errors:     p.errs,
+	}
+	if l := tpccP95.FindStringSubmatch(line); l != nil {
+		s.Latency = parseFloat(l[1])
+	}
+	p.errs = 0
+	return s, true
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -24,6 +24,7 @@ type TestTp int
 const (
 	OltpInsert TestTp = iota
 	OltpReadWrite
+	OltpPointSelect
 )
 
 func GetSysbenchTpValue(tp TestTp) string {
@@ -32,10 +33,22 @@ func GetSysbenchTpValue(tp TestTp) string {
 		return "oltp_insert"
 	case OltpReadWrite:
 		return "oltp_read_write"
+	case OltpPointSelect:
+		return "oltp_point_select"
 	}
 	return ""
 }
 
+// GetSysbenchTp is the reverse of GetSysbenchTpValue.
+func GetSysbenchTp(v string) (TestTp, bool) {
+	for _, tp := range []TestTp{OltpInsert, OltpReadWrite, OltpPointSelect} {
+		if GetSysbenchTpValue(tp) == v {
+			return tp, true
+		}
+	}
+	return 0, false
+}
+
 type Sysbench struct {
 	Test      TestTp
 	Db        string
@@ -85,7 +98,7 @@ func InstallSysBench() error {
 		}
 
 		if _, err := TestSysbench(); err != nil {
-			log.Logger.Info("[%s] install sysbench failed.")
+			log.Logger.Infof("[%s] install sysbench failed.", ov)
 		} else {
 			log.Logger.Infof("[%s] install sysbench complete.", ov)
 		}
@@ -116,11 +129,5 @@ func (s *Sysbench) String() string {
 		s.Tables,
 		s.Threads,
 		s.Cmd)
-	switch s.Test {
-	case OltpInsert:
-		cmd = strings.Replace(cmd, "${test_type}", GetSysbenchTpValue(OltpInsert), -1)
-	case OltpReadWrite:
-		cmd = strings.Replace(cmd, "${test_type}", GetSysbenchTpValue(OltpReadWrite), -1)
-	}
-	return cmd
+	return strings.Replace(cmd, "${test_type}", GetSysbenchTpValue(s.Test), -1)
 }
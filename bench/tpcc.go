@@ -1,33 +1,851 @@
 package bench
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math/rand"
+	"pictorial/log"
 	"pictorial/mysql"
-	"pictorial/ssh"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// the cardinalities of tpcc, the data of a warehouse are about 70MB.
+const (
+	tpccItems     = 100000
+	tpccDistricts = 10
+	tpccCustomers = 3000
+	tpccOrders    = 3000
+	// the orders from tpccNewOrders are not delivered yet.
+	tpccNewOrders = 2101
+	tpccBatch     = 500
+)
+
+// the constants C of NURand, cLastRun - cLastLoad is in [65, 119] and not 96 or 112.
+const (
+	cLastLoad = 157
+	cLastRun  = 223
+	cID       = 259
+	cItem     = 7911
+)
+
+// the transactions of tpcc and their percentages of the mix.
+const (
+	newOrder = iota
+	payment
+	orderStatus
+	delivery
+	stockLevel
+)
+
+var (
+	tpccTxns = []string{"new_order", "payment", "order_status", "delivery", "stock_level"}
+	tpccMix  = []int{45, 43, 4, 4, 4}
+)
+
+var tpccTables = []string{"warehouse", "district", "customer", "history", "new_order", "orders", "order_line", "stock", "item"}
+
+var tpccSchema = []string{
+	`CREATE TABLE IF NOT EXISTS %s.warehouse (w_id INT NOT NULL, w_name VARCHAR(10), w_street_1 VARCHAR(20), w_street_2 VARCHAR(20),
+	w_city VARCHAR(20), w_state CHAR(2), w_zip CHAR(9), w_tax DECIMAL(4,4), w_ytd DECIMAL(12,2), PRIMARY KEY (w_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.district (d_id INT NOT NULL, d_w_id INT NOT NULL, d_name VARCHAR(10), d_street_1 VARCHAR(20),
+	d_street_2 VARCHAR(20), d_city VARCHAR(20), d_state CHAR(2), d_zip CHAR(9), d_tax DECIMAL(4,4), d_ytd DECIMAL(12,2),
+	d_next_o_id INT, PRIMARY KEY (d_w_id, d_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.customer (c_id INT NOT NULL, c_d_id INT NOT NULL, c_w_id INT NOT NULL, c_first VARCHAR(16),
+	c_middle CHAR(2), c_last VARCHAR(16), c_street_1 VARCHAR(20), c_street_2 VARCHAR(20), c_city VARCHAR(20), c_state CHAR(2),
+	c_zip CHAR(9), c_phone CHAR(16), c_since DATETIME, c_credit CHAR(2), c_credit_lim DECIMAL(12,2), c_discount DECIMAL(4,4),
+	c_balance DECIMAL(12,2), c_ytd_payment DECIMAL(12,2), c_payment_cnt INT, c_delivery_cnt INT, c_data VARCHAR(500),
+	PRIMARY KEY (c_w_id, c_d_id, c_id), INDEX idx_customer (c_w_id, c_d_id, c_last, c_first))`,
+	`CREATE TABLE IF NOT EXISTS %s.history (h_c_id INT NOT NULL, h_c_d_id INT NOT NULL, h_c_w_id INT NOT NULL, h_d_id INT NOT NULL,
+	h_w_id INT NOT NULL, h_date DATETIME, h_amount DECIMAL(6,2), h_data VARCHAR(24),
+	INDEX idx_h_w_id (h_w_id), INDEX idx_h_c_w_id (h_c_w_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.new_order (no_o_id INT NOT NULL, no_d_id INT NOT NULL, no_w_id INT NOT NULL,
+	PRIMARY KEY (no_w_id, no_d_id, no_o_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.orders (o_id INT NOT NULL, o_d_id INT NOT NULL, o_w_id INT NOT NULL, o_c_id INT NOT NULL,
+	o_entry_d DATETIME, o_carrier_id INT, o_ol_cnt INT, o_all_local INT, PRIMARY KEY (o_w_id, o_d_id, o_id),
+	INDEX idx_order (o_w_id, o_d_id, o_c_id, o_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.order_line (ol_o_id INT NOT NULL, ol_d_id INT NOT NULL, ol_w_id INT NOT NULL, ol_number INT NOT NULL,
+	ol_i_id INT NOT NULL, ol_supply_w_id INT, ol_delivery_d DATETIME, ol_quantity INT, ol_amount DECIMAL(6,2), ol_dist_info CHAR(24),
+	PRIMARY KEY (ol_w_id, ol_d_id, ol_o_id, ol_number))`,
+	`CREATE TABLE IF NOT EXISTS %s.stock (s_i_id INT NOT NULL, s_w_id INT NOT NULL, s_quantity INT, s_dist_01 CHAR(24), s_dist_02 CHAR(24),
+	s_dist_03 CHAR(24), s_dist_04 CHAR(24), s_dist_05 CHAR(24), s_dist_06 CHAR(24), s_dist_07 CHAR(24), s_dist_08 CHAR(24),
+	s_dist_09 CHAR(24), s_dist_10 CHAR(24), s_ytd INT, s_order_cnt INT, s_remote_cnt INT, s_data VARCHAR(50),
+	PRIMARY KEY (s_w_id, s_i_id))`,
+	`CREATE TABLE IF NOT EXISTS %s.item (i_id INT NOT NULL, i_im_id INT, i_name VARCHAR(24), i_price DECIMAL(5,2), i_data VARCHAR(50),
+	PRIMARY KEY (i_id))`,
+}
+
+// tpccChecks are the consistency conditions of the tpcc spec 3.3.2, each of them counts the violations.
+var tpccChecks = []struct {
+	name string
+	sql  string
+}{
+	{"W_YTD = sum(D_YTD)", `SELECT COUNT(*) FROM (SELECT w_id FROM %[1]s.warehouse JOIN %[1]s.district ON d_w_id = w_id
+	GROUP BY w_id, w_ytd HAVING w_ytd != SUM(d_ytd)) t`},
+	{"D_NEXT_O_ID - 1 = max(O_ID)", `SELECT COUNT(*) FROM %[1]s.district JOIN (SELECT o_w_id, o_d_id, MAX(o_id) m FROM %[1]s.orders
+	GROUP BY o_w_id, o_d_id) o ON d_w_id = o_w_id AND d_id = o_d_id WHERE d_next_o_id - 1 != m`},
+	{"D_NEXT_O_ID - 1 = max(NO_O_ID)", `SELECT COUNT(*) FROM %[1]s.district JOIN (SELECT no_w_id, no_d_id, MAX(no_o_id) m FROM %[1]s.new_order
+	GROUP BY no_w_id, no_d_id) n ON d_w_id = no_w_id AND d_id = no_d_id WHERE d_next_o_id - 1 != m`},
+	{"max(NO_O_ID) - min(NO_O_ID) + 1 = count(NO_O_ID)", `SELECT COUNT(*) FROM (SELECT no_w_id FROM %[1]s.new_order
+	GROUP BY no_w_id, no_d_id HAVING MAX(no_o_id) - MIN(no_o_id) + 1 != COUNT(*)) t`},
+	{"sum(O_OL_CNT) = count(OL_O_ID)", `SELECT COUNT(*) FROM (SELECT o_w_id, o_d_id, SUM(o_ol_cnt) s FROM %[1]s.orders GROUP BY o_w_id, o_d_id) o
+	JOIN (SELECT ol_w_id, ol_d_id, COUNT(*) c FROM %[1]s.order_line GROUP BY ol_w_id, ol_d_id) l
+	ON o_w_id = ol_w_id AND o_d_id = ol_d_id WHERE s != c`},
+	{"O_CARRIER_ID is null for the new orders", `SELECT COUNT(*) FROM %[1]s.orders LEFT JOIN %[1]s.new_order
+	ON no_w_id = o_w_id AND no_d_id = o_d_id AND no_o_id = o_id WHERE (o_carrier_id IS NULL) != (no_o_id IS NOT NULL)`},
+	{"OL_DELIVERY_D is null for the new orders", `SELECT COUNT(*) FROM %[1]s.orders JOIN %[1]s.order_line
+	ON ol_w_id = o_w_id AND ol_d_id = o_d_id AND ol_o_id = o_id WHERE (o_carrier_id IS NULL) != (ol_delivery_d IS NULL)`},
+	{"W_YTD = sum(H_AMOUNT)", `SELECT COUNT(*) FROM %[1]s.warehouse JOIN (SELECT h_w_id, SUM(h_amount) s FROM %[1]s.history
+	GROUP BY h_w_id) h ON w_id = h_w_id WHERE w_ytd != s`},
+	{"D_YTD = sum(H_AMOUNT)", `SELECT COUNT(*) FROM %[1]s.district JOIN (SELECT h_w_id, h_d_id, SUM(h_amount) s FROM %[1]s.history
+	GROUP BY h_w_id, h_d_id) h ON d_w_id = h_w_id AND d_id = h_d_id WHERE d_ytd != s`},
+}
+
+// Tpcc runs tpcc in tipoc like go-tpc, the cmd is prepare, run, check or cleanup.
+// Every thread has a home warehouse and runs the mix of the five transactions without keying and thinking time.
 type Tpcc struct {
 	Mysql      mysql.MySQL
 	DB         string
 	Warehouses int
 	Threads    int
 	Cmd        string
+	// Duration is the time of run, it runs until the context is done if 0.
+	Duration time.Duration
 }
 
 func (t *Tpcc) String() string {
-	return fmt.Sprintf("tiup bench tpcc --host %s --port %s --user %s --password '%s' --warehouses %d --threads %d %s;",
-		mysql.M.Host,
-		mysql.M.Port,
-		mysql.M.User,
-		mysql.M.Password,
-		t.Warehouses,
-		t.Threads,
-		t.Cmd)
+	s := fmt.Sprintf("tpcc %s --db=%s --warehouses=%d --threads=%d", t.cmd(), t.DB, t.warehouses(), t.threads())
+	if t.Duration != 0 {
+		s += fmt.Sprintf(" --time=%s", t.Duration)
+	}
+	return s
+}
+
+func (t *Tpcc) cmd() string {
+	if t.Cmd == "" {
+		return CmdRun
+	}
+	return t.Cmd
+}
+
+func (t *Tpcc) warehouses() int {
+	if t.Warehouses <= 0 {
+		return 1
+	}
+	return t.Warehouses
+}
+
+func (t *Tpcc) threads() int {
+	if t.Threads <= 0 {
+		return 1
+	}
+	return t.Threads
 }
 
-func (t *Tpcc) Run() error {
-	if _, err := ssh.S.RunLocal(t.String()); err != nil {
+func (t *Tpcc) table(name string) string {
+	return t.DB + "." + name
+}
+
+func (t *Tpcc) session() *mysql.Session {
+	s, _ := t.Mysql.NewSessionWithTimeout(t.Mysql.User, t.Mysql.Password, oltpTimeout)
+	return s
+}
+
+// Exec runs the cmd, run prepares the data first if it is not loaded.
+func (t *Tpcc) Exec(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	switch t.cmd() {
+	case CmdPrepare:
+		return nil, t.Prepare(ctx)
+	case CmdCheck:
+		return nil, t.Check(ctx, w)
+	case CmdCleanup:
+		return nil, t.Cleanup()
+	case CmdRun:
+		if err := t.Prepare(ctx); err != nil {
+			return nil, err
+		}
+		return t.Run(ctx, w, report)
+	}
+	return nil, fmt.Errorf("%s is not supported by tpcc", t.cmd())
+}
+
+// Cleanup drops the tables.
+func (t *Tpcc) Cleanup() error {
+	s := t.session()
+	defer s.Close()
+	for _, tbl := range tpccTables {
+		if r := s.Execute("DROP TABLE IF EXISTS " + t.table(tbl)); r.Failed() {
+			return fmt.Errorf("[tpcc] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// Prepare creates the tables and loads the warehouses which are not loaded yet,
+// the warehouse row is inserted at last, so a warehouse interrupted in the middle is loaded again.
+func (t *Tpcc) Prepare(ctx context.Context) error {
+	s := t.session()
+	defer s.Close()
+	stmts := []string{fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", t.DB)}
+	for _, ddl := range tpccSchema {
+		stmts = append(stmts, fmt.Sprintf(ddl, t.DB))
+	}
+	for _, stmt := range stmts {
+		if r := s.Execute(stmt); r.Failed() {
+			return fmt.Errorf("[tpcc] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	r := s.Execute(fmt.Sprintf("SELECT w_id FROM %s", t.table("warehouse")))
+	if r.Failed() {
+		return fmt.Errorf("[tpcc] %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	loaded := make(map[int]bool)
+	for _, row := range r.Rows {
+		loaded[atoi(row[0])] = true
+	}
+	var todo []int
+	for w := 1; w <= t.warehouses(); w++ {
+		if !loaded[w] {
+			todo = append(todo, w)
+		}
+	}
+	if len(todo) == 0 {
+		log.Logger.Infof("[tpcc] %d warehouses are loaded.", t.warehouses())
+		return nil
+	}
+	if r := s.Execute(fmt.Sprintf("SELECT i_id FROM %s LIMIT 1", t.table("item"))); r.Failed() {
+		return fmt.Errorf("[tpcc] %d: %s", r.ErrCode, r.ErrMsg)
+	} else if len(r.Rows) == 0 {
+		log.Logger.Infof("[tpcc] load %d items", tpccItems)
+		if err := t.loadItems(ctx, s, newRand(0)); err != nil {
+			return err
+		}
+	}
+
+	wC := make(chan int)
+	errC := make(chan error, t.threads())
+	var wg sync.WaitGroup
+	for i := 0; i < t.threads(); i++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			s := t.session()
+			defer s.Close()
+			for w := range wC {
+				log.Logger.Infof("[tpcc] load warehouse %d", w)
+				if err := t.loadWarehouse(ctx, s, r, w); err != nil {
+					errC <- err
+					return
+				}
+			}
+		}(newRand(int64(i + 1)))
+	}
+	var err error
+loop:
+	for _, w := range todo {
+		select {
+		case wC <- w:
+		case err = <-errC:
+			break loop
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		}
+	}
+	close(wC)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errC:
+		default:
+		}
+	}
+	if err == nil {
+		log.Logger.Infof("[tpcc] %d warehouses are loaded.", t.warehouses())
+	}
+	return err
+}
+
+// insert writes the rows in batches.
+func (t *Tpcc) insert(ctx context.Context, s *mysql.Session, tbl string, n int, row func(i int) string) error {
+	values := make([]string, 0, tpccBatch)
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r := s.Execute(fmt.Sprintf("INSERT INTO %s VALUES %s", t.table(tbl), strings.Join(values, ",")))
+		values = values[:0]
+		if r.Failed() {
+			return fmt.Errorf("[tpcc] insert into %s failed, %d: %s", tbl, r.ErrCode, r.ErrMsg)
+		}
+		return nil
+	}
+	for i := 1; i <= n; i++ {
+		values = append(values, row(i))
+		if len(values) == tpccBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+func (t *Tpcc) loadItems(ctx context.Context, s *mysql.Session, r *rand.Rand) error {
+	return t.insert(ctx, s, "item", tpccItems, func(i int) string {
+		return fmt.Sprintf("(%d,%d,'%s',%.2f,'%s')", i, randInt(r, 1, 10000), aString(r, 14, 24),
+			float64(randInt(r, 100, 10000))/100, original(r))
+	})
+}
+
+func (t *Tpcc) loadWarehouse(ctx context.Context, s *mysql.Session, r *rand.Rand, w int) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	// the data of an interrupted load are removed first
+	for _, stmt := range []string{
+		fmt.Sprintf("DELETE FROM %s WHERE s_w_id = %d", t.table("stock"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE d_w_id = %d", t.table("district"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE c_w_id = %d", t.table("customer"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE h_w_id = %d", t.table("history"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE o_w_id = %d", t.table("orders"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE ol_w_id = %d", t.table("order_line"), w),
+		fmt.Sprintf("DELETE FROM %s WHERE no_w_id = %d", t.table("new_order"), w),
+	} {
+		if res := s.Execute(stmt); res.Failed() {
+			return fmt.Errorf("[tpcc] %d: %s", res.ErrCode, res.ErrMsg)
+		}
+	}
+	if err := t.insert(ctx, s, "stock", tpccItems, func(i int) string {
+		dists := make([]string, tpccDistricts)
+		for d := range dists {
+			dists[d] = "'" + aString(r, 24, 24) + "'"
+		}
+		return fmt.Sprintf("(%d,%d,%d,%s,0,0,0,'%s')", i, w, randInt(r, 10, 100), strings.Join(dists, ","), original(r))
+	}); err != nil {
+		return err
+	}
+	if err := t.insert(ctx, s, "district", tpccDistricts, func(d int) string {
+		return fmt.Sprintf("(%d,%d,'%s',%s,%.4f,30000.00,%d)", d, w, aString(r, 6, 10), address(r),
+			float64(randInt(r, 0, 2000))/10000, tpccOrders+1)
+	}); err != nil {
 		return err
 	}
+	for d := 1; d <= tpccDistricts; d++ {
+		if err := t.insert(ctx, s, "customer", tpccCustomers, func(c int) string {
+			last := lastName(c - 1)
+			if c > 1000 {
+				last = lastName(nuRand(r, 255, 0, 999, cLastLoad))
+			}
+			credit := "GC"
+			if r.Intn(10) == 0 {
+				credit = "BC"
+			}
+			return fmt.Sprintf("(%d,%d,%d,'%s','OE','%s',%s,'%s','%s','%s',50000.00,%.4f,-10.00,10.00,1,0,'%s')",
+				c, d, w, aString(r, 8, 16), last, address(r), nString(r, 16, 16), now, credit,
+				float64(randInt(r, 0, 5000))/10000, aString(r, 300, 500))
+		}); err != nil {
+			return err
+		}
+		if err := t.insert(ctx, s, "history", tpccCustomers, func(c int) string {
+			return fmt.Sprintf("(%d,%d,%d,%d,%d,'%s',10.00,'%s')", c, d, w, d, w, now, aString(r, 12, 24))
+		}); err != nil {
+			return err
+		}
+		customers := r.Perm(tpccCustomers)
+		olCnt := make([]int, tpccOrders+1)
+		if err := t.insert(ctx, s, "orders", tpccOrders, func(o int) string {
+			olCnt[o] = randInt(r, 5, 15)
+			carrier := "NULL"
+			if o < tpccNewOrders {
+				carrier = strconv.Itoa(randInt(r, 1, 10))
+			}
+			return fmt.Sprintf("(%d,%d,%d,%d,'%s',%s,%d,1)", o, d, w, customers[o-1]+1, now, carrier, olCnt[o])
+		}); err != nil {
+			return err
+		}
+		var lines []string
+		for o := 1; o <= tpccOrders; o++ {
+			for n := 1; n <= olCnt[o]; n++ {
+				delivery, amount := "'"+now+"'", "0.00"
+				if o >= tpccNewOrders {
+					delivery, amount = "NULL", fmt.Sprintf("%.2f", float64(randInt(r, 1, 999999))/100)
+				}
+				lines = append(lines, fmt.Sprintf("(%d,%d,%d,%d,%d,%d,%s,5,%s,'%s')",
+					o, d, w, n, randInt(r, 1, tpccItems), w, delivery, amount, aString(r, 24, 24)))
+			}
+		}
+		if err := t.insert(ctx, s, "order_line", len(lines), func(i int) string {
+			return lines[i-1]
+		}); err != nil {
+			return err
+		}
+		if err := t.insert(ctx, s, "new_order", tpccOrders-tpccNewOrders+1, func(i int) string {
+			return fmt.Sprintf("(%d,%d,%d)", tpccNewOrders+i-1, d, w)
+		}); err != nil {
+			return err
+		}
+	}
+	return t.insert(ctx, s, "warehouse", 1, func(int) string {
+		return fmt.Sprintf("(%d,'%s',%s,%.4f,300000.00)", w, aString(r, 6, 10), address(r), float64(randInt(r, 0, 2000))/10000)
+	})
+}
+
+// Run runs the transaction mix until the duration or the context is done, the tps and the latency of the samples
+// are of new_order as tiup bench tpcc, the summary has the tpmC and the result of each transaction.
+func (t *Tpcc) Run(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	m := newMeter(newOrder, tpccTxns...)
+	drive(ctx, t.Duration, t.threads(), m, w, report, func(ctx context.Context, n int) {
+		r := newRand(int64(n))
+		s := t.session()
+		defer s.Close()
+		home := n%t.warehouses() + 1
+		for ctx.Err() == nil {
+			kind := pickTxn(r)
+			tx := &tpccTxn{t: t, s: s, r: r, w: home}
+			start := time.Now()
+			err := tx.run(kind)
+			if ctx.Err() != nil {
+				return
+			}
+			m.add(kind, time.Since(start), tx.queries, err)
+			if tx.lost {
+				time.Sleep(errorBackoff)
+			}
+		}
+	})
+	sum, hist := m.summary()
+	if elapsed := m.last.Sub(m.begin).Minutes(); elapsed > 0 {
+		sum.TpmC = float64(sum.Details[newOrder].Count) / elapsed
+	}
+	sum.write(w, "tpcc")
+	hist.Write(w)
+	return sum, nil
+}
+
+// Check counts the violations of the consistency conditions, it fails if any.
+func (t *Tpcc) Check(ctx context.Context, w io.Writer) error {
+	s := t.session()
+	defer s.Close()
+	var failed []string
+	for i, c := range tpccChecks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r := s.Execute(fmt.Sprintf(c.sql, t.DB))
+		switch {
+		case r.Failed():
+			fmt.Fprintf(w, "[tpcc] check %d %s: error %d: %s\n", i+1, c.name, r.ErrCode, r.ErrMsg)
+			failed = append(failed, fmt.Sprintf("%s: %s", c.name, r.ErrMsg))
+		case len(r.Rows) == 0 || r.Rows[0][0] != "0":
+			n := "?"
+			if len(r.Rows) != 0 {
+				n = r.Rows[0][0]
+			}
+			fmt.Fprintf(w, "[tpcc] check %d %s: %s violations\n", i+1, c.name, n)
+			failed = append(failed, fmt.Sprintf("%s: %s violations", c.name, n))
+		default:
+			fmt.Fprintf(w, "[tpcc] check %d %s: ok\n", i+1, c.name)
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("tpcc check failed, %s", strings.Join(failed, "; "))
+	}
 	return nil
 }
+
+func pickTxn(r *rand.Rand) int {
+	n := r.Intn(100)
+	for i, p := range tpccMix {
+		if n < p {
+			return i
+		}
+		n -= p
+	}
+	return newOrder
+}
+
+// tpccTxn is one transaction of a thread, w is the home warehouse.
+type tpccTxn struct {
+	t       *Tpcc
+	s       *mysql.Session
+	r       *rand.Rand
+	w       int
+	queries int
+	lost    bool
+	began   bool
+}
+
+// errRollback is the 1% new orders with an unused item, which are rolled back on purpose.
+var errRollback = fmt.Errorf("rollback")
+
+func (x *tpccTxn) run(kind int) error {
+	var err error
+	switch kind {
+	case newOrder:
+		err = x.newOrder()
+	case payment:
+		err = x.payment()
+	case orderStatus:
+		err = x.orderStatus()
+	case delivery:
+		err = x.delivery()
+	case stockLevel:
+		err = x.stockLevel()
+	}
+	if err == errRollback {
+		_, err = x.exec("ROLLBACK")
+		return err
+	}
+	if err != nil {
+		if x.began && !x.lost {
+			x.s.Execute("ROLLBACK")
+		}
+		return err
+	}
+	_, err = x.exec("COMMIT")
+	return err
+}
+
+func (x *tpccTxn) exec(format string, args ...interface{}) ([][]string, error) {
+	sql := format
+	if len(args) != 0 {
+		sql = fmt.Sprintf(format, args...)
+	}
+	x.queries++
+	r := x.s.Execute(sql)
+	if r.Failed() {
+		x.lost = r.ConnectionLost()
+		return nil, fmt.Errorf("%d: %s", r.ErrCode, r.ErrMsg)
+	}
+	if sql == "BEGIN" {
+		x.began = true
+	}
+	return r.Rows, nil
+}
+
+func (x *tpccTxn) tbl(name string) string {
+	return x.t.table(name)
+}
+
+// remote is another warehouse, or the home one if there is only one.
+func (x *tpccTxn) remote() int {
+	if x.t.warehouses() == 1 {
+		return x.w
+	}
+	for {
+		if w := randInt(x.r, 1, x.t.warehouses()); w != x.w {
+			return w
+		}
+	}
+}
+
+func (x *tpccTxn) newOrder() error {
+	r := x.r
+	d := randInt(r, 1, tpccDistricts)
+	c := nuRand(r, 1023, 1, tpccCustomers, cID)
+	cnt := randInt(r, 5, 15)
+	rbk := r.Intn(100) == 0
+	type line struct {
+		item, supply, quantity int
+	}
+	lines := make([]line, cnt)
+	allLocal := 1
+	for i := range lines {
+		lines[i] = line{item: nuRand(r, 8191, 1, tpccItems, cItem), supply: x.w, quantity: randInt(r, 1, 10)}
+		if r.Intn(100) == 0 {
+			lines[i].supply = x.remote()
+			if lines[i].supply != x.w {
+				allLocal = 0
+			}
+		}
+	}
+	if rbk {
+		lines[cnt-1].item = tpccItems + 1
+	}
+	// the stocks are locked in order to avoid the deadlocks between the new orders
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].supply != lines[j].supply {
+			return lines[i].supply < lines[j].supply
+		}
+		return lines[i].item < lines[j].item
+	})
+	if _, err := x.exec("BEGIN"); err != nil {
+		return err
+	}
+	if _, err := x.exec("SELECT c_discount, c_last, c_credit, w_tax FROM %s, %s WHERE w_id = %d AND c_w_id = w_id AND c_d_id = %d AND c_id = %d",
+		x.tbl("customer"), x.tbl("warehouse"), x.w, d, c); err != nil {
+		return err
+	}
+	rows, err := x.exec("SELECT d_next_o_id, d_tax FROM %s WHERE d_w_id = %d AND d_id = %d FOR UPDATE", x.tbl("district"), x.w, d)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("district %d of warehouse %d is not found", d, x.w)
+	}
+	o := atoi(rows[0][0])
+	if _, err := x.exec("UPDATE %s SET d_next_o_id = %d WHERE d_w_id = %d AND d_id = %d", x.tbl("district"), o+1, x.w, d); err != nil {
+		return err
+	}
+	if _, err := x.exec("INSERT INTO %s (o_id, o_d_id, o_w_id, o_c_id, o_entry_d, o_ol_cnt, o_all_local) VALUES (%d,%d,%d,%d,NOW(),%d,%d)",
+		x.tbl("orders"), o, d, x.w, c, cnt, allLocal); err != nil {
+		return err
+	}
+	if _, err := x.exec("INSERT INTO %s (no_o_id, no_d_id, no_w_id) VALUES (%d,%d,%d)", x.tbl("new_order"), o, d, x.w); err != nil {
+		return err
+	}
+	var values []string
+	for i, l := range lines {
+		items, err := x.exec("SELECT i_price FROM %s WHERE i_id = %d", x.tbl("item"), l.item)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return errRollback
+		}
+		stocks, err := x.exec("SELECT s_quantity, s_dist_%02d FROM %s WHERE s_w_id = %d AND s_i_id = %d FOR UPDATE",
+			d, x.tbl("stock"), l.supply, l.item)
+		if err != nil {
+			return err
+		}
+		if len(stocks) == 0 {
+			return fmt.Errorf("stock %d of warehouse %d is not found", l.item, l.supply)
+		}
+		q := atoi(stocks[0][0])
+		if q >= l.quantity+10 {
+			q -= l.quantity
+		} else {
+			q = q - l.quantity + 91
+		}
+		remote := 0
+		if l.supply != x.w {
+			remote = 1
+		}
+		if _, err := x.exec("UPDATE %s SET s_quantity = %d, s_ytd = s_ytd + %d, s_order_cnt = s_order_cnt + 1, s_remote_cnt = s_remote_cnt + %d WHERE s_w_id = %d AND s_i_id = %d",
+			x.tbl("stock"), q, l.quantity, remote, l.supply, l.item); err != nil {
+			return err
+		}
+		price, _ := strconv.ParseFloat(items[0][0], 64)
+		values = append(values, fmt.Sprintf("(%d,%d,%d,%d,%d,%d,NULL,%d,%.2f,'%s')",
+			o, d, x.w, i+1, l.item, l.supply, l.quantity, price*float64(l.quantity), stocks[0][1]))
+	}
+	_, err = x.exec("INSERT INTO %s (ol_o_id, ol_d_id, ol_w_id, ol_number, ol_i_id, ol_supply_w_id, ol_delivery_d, ol_quantity, ol_amount, ol_dist_info) VALUES %s",
+		x.tbl("order_line"), strings.Join(values, ","))
+	return err
+}
+
+// customer selects the customer by the last name in 60% or by the id, it returns the id.
+func (x *tpccTxn) customer(w, d int) (int, error) {
+	if x.r.Intn(100) >= 60 {
+		return nuRand(x.r, 1023, 1, tpccCustomers, cID), nil
+	}
+	rows, err := x.exec("SELECT c_id FROM %s WHERE c_w_id = %d AND c_d_id = %d AND c_last = '%s' ORDER BY c_first",
+		x.tbl("customer"), w, d, lastName(nuRand(x.r, 255, 0, 999, cLastRun)))
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return nuRand(x.r, 1023, 1, tpccCustomers, cID), nil
+	}
+	return atoi(rows[(len(rows)-1)/2][0]), nil
+}
+
+func (x *tpccTxn) payment() error {
+	r := x.r
+	d := randInt(r, 1, tpccDistricts)
+	cw, cd := x.w, d
+	if r.Intn(100) >= 85 {
+		cw, cd = x.remote(), randInt(r, 1, tpccDistricts)
+	}
+	amount := float64(randInt(r, 100, 500000)) / 100
+	if _, err := x.exec("BEGIN"); err != nil {
+		return err
+	}
+	if _, err := x.exec("UPDATE %s SET w_ytd = w_ytd + %.2f WHERE w_id = %d", x.tbl("warehouse"), amount, x.w); err != nil {
+		return err
+	}
+	if _, err := x.exec("SELECT w_name FROM %s WHERE w_id = %d", x.tbl("warehouse"), x.w); err != nil {
+		return err
+	}
+	if _, err := x.exec("UPDATE %s SET d_ytd = d_ytd + %.2f WHERE d_w_id = %d AND d_id = %d", x.tbl("district"), amount, x.w, d); err != nil {
+		return err
+	}
+	if _, err := x.exec("SELECT d_name FROM %s WHERE d_w_id = %d AND d_id = %d", x.tbl("district"), x.w, d); err != nil {
+		return err
+	}
+	c, err := x.customer(cw, cd)
+	if err != nil {
+		return err
+	}
+	rows, err := x.exec("SELECT c_credit, c_data FROM %s WHERE c_w_id = %d AND c_d_id = %d AND c_id = %d FOR UPDATE",
+		x.tbl("customer"), cw, cd, c)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("customer %d of district %d, warehouse %d is not found", c, cd, cw)
+	}
+	if rows[0][0] == "BC" {
+		data := fmt.Sprintf("%d %d %d %d %d %.2f|%s", c, cd, cw, d, x.w, amount, rows[0][1])
+		if len(data) > 500 {
+			data = data[:500]
+		}
+		_, err = x.exec("UPDATE %s SET c_balance = c_balance - %.2f, c_ytd_payment = c_ytd_payment + %.2f, c_payment_cnt = c_payment_cnt + 1, c_data = '%s' WHERE c_w_id = %d AND c_d_id = %d AND c_id = %d",
+			x.tbl("customer"), amount, amount, data, cw, cd, c)
+	} else {
+		_, err = x.exec("UPDATE %s SET c_balance = c_balance - %.2f, c_ytd_payment = c_ytd_payment + %.2f, c_payment_cnt = c_payment_cnt + 1 WHERE c_w_id = %d AND c_d_id = %d AND c_id = %d",
+			x.tbl("customer"), amount, amount, cw, cd, c)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = x.exec("INSERT INTO %s (h_c_id, h_c_d_id, h_c_w_id, h_d_id, h_w_id, h_date, h_amount, h_data) VALUES (%d,%d,%d,%d,%d,NOW(),%.2f,'%s')",
+		x.tbl("history"), c, cd, cw, d, x.w, amount, aString(r, 12, 24))
+	return err
+}
+
+func (x *tpccTxn) orderStatus() error {
+	d := randInt(x.r, 1, tpccDistricts)
+	if _, err := x.exec("BEGIN"); err != nil {
+		return err
+	}
+	c, err := x.customer(x.w, d)
+	if err != nil {
+		return err
+	}
+	if _, err := x.exec("SELECT c_balance, c_first, c_middle, c_last FROM %s WHERE c_w_id = %d AND c_d_id = %d AND c_id = %d",
+		x.tbl("customer"), x.w, d, c); err != nil {
+		return err
+	}
+	rows, err := x.exec("SELECT o_id, o_carrier_id, o_entry_d FROM %s WHERE o_w_id = %d AND o_d_id = %d AND o_c_id = %d ORDER BY o_id DESC LIMIT 1",
+		x.tbl("orders"), x.w, d, c)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	_, err = x.exec("SELECT ol_i_id, ol_supply_w_id, ol_quantity, ol_amount, ol_delivery_d FROM %s WHERE ol_w_id = %d AND ol_d_id = %d AND ol_o_id = %s",
+		x.tbl("order_line"), x.w, d, rows[0][0])
+	return err
+}
+
+func (x *tpccTxn) delivery() error {
+	carrier := randInt(x.r, 1, 10)
+	if _, err := x.exec("BEGIN"); err != nil {
+		return err
+	}
+	for d := 1; d <= tpccDistricts; d++ {
+		rows, err := x.exec("SELECT no_o_id FROM %s WHERE no_w_id = %d AND no_d_id = %d ORDER BY no_o_id LIMIT 1 FOR UPDATE",
+			x.tbl("new_order"), x.w, d)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		o := rows[0][0]
+		if _, err := x.exec("DELETE FROM %s WHERE no_w_id = %d AND no_d_id = %d AND no_o_id = %s", x.tbl("new_order"), x.w, d, o); err != nil {
+			return err
+		}
+		rows, err = x.exec("SELECT o_c_id FROM %s WHERE o_w_id = %d AND o_d_id = %d AND o_id = %s", x.tbl("orders"), x.w, d, o)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("order %s of district %d, warehouse %d is not found", o, d, x.w)
+		}
+		c := rows[0][0]
+		if _, err := x.exec("UPDATE %s SET o_carrier_id = %d WHERE o_w_id = %d AND o_d_id = %d AND o_id = %s",
+			x.tbl("orders"), carrier, x.w, d, o); err != nil {
+			return err
+		}
+		if _, err := x.exec("UPDATE %s SET ol_delivery_d = NOW() WHERE ol_w_id = %d AND ol_d_id = %d AND ol_o_id = %s",
+			x.tbl("order_line"), x.w, d, o); err != nil {
+			return err
+		}
+		rows, err = x.exec("SELECT COALESCE(SUM(ol_amount), 0) FROM %s WHERE ol_w_id = %d AND ol_d_id = %d AND ol_o_id = %s",
+			x.tbl("order_line"), x.w, d, o)
+		if err != nil {
+			return err
+		}
+		if _, err := x.exec("UPDATE %s SET c_balance = c_balance + %s, c_delivery_cnt = c_delivery_cnt + 1 WHERE c_w_id = %d AND c_d_id = %d AND c_id = %s",
+			x.tbl("customer"), rows[0][0], x.w, d, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *tpccTxn) stockLevel() error {
+	d := randInt(x.r, 1, tpccDistricts)
+	threshold := randInt(x.r, 10, 20)
+	if _, err := x.exec("BEGIN"); err != nil {
+		return err
+	}
+	rows, err := x.exec("SELECT d_next_o_id FROM %s WHERE d_w_id = %d AND d_id = %d", x.tbl("district"), x.w, d)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	o := atoi(rows[0][0])
+	_, err = x.exec("SELECT COUNT(DISTINCT s_i_id) FROM %s, %s WHERE ol_w_id = %d AND ol_d_id = %d AND ol_o_id < %d AND ol_o_id >= %d AND s_w_id = %d AND s_i_id = ol_i_id AND s_quantity < %d",
+		x.tbl("order_line"), x.tbl("stock"), x.w, d, o, o-20, x.w, threshold)
+	return err
+}
+
+var syllables = []string{"BAR", "OUGHT", "ABLE", "PRI", "PRES", "ESE", "ANTI", "CALLY", "ATION", "EING"}
+
+// lastName is the c_last of the number in [0, 999].
+func lastName(n int) string {
+	return syllables[n/100] + syllables[n/10%10] + syllables[n%10]
+}
+
+func nuRand(r *rand.Rand, a, x, y, c int) int {
+	return ((randInt(r, 0, a)|randInt(r, x, y))+c)%(y-x+1) + x
+}
+
+func randInt(r *rand.Rand, min, max int) int {
+	return min + r.Intn(max-min+1)
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func aString(r *rand.Rand, min, max int) string {
+	b := make([]byte, randInt(r, min, max))
+	for i := range b {
+		b[i] = alphanumeric[r.Intn(len(alphanumeric))]
+	}
+	return string(b)
+}
+
+func nString(r *rand.Rand, min, max int) string {
+	b := make([]byte, randInt(r, min, max))
+	for i := range b {
+		b[i] = byte('0' + r.Intn(10))
+	}
+	return string(b)
+}
+
+// original is the i_data and s_data, 10% of them contain ORIGINAL.
+func original(r *rand.Rand) string {
+	s := aString(r, 26, 50)
+	if r.Intn(10) == 0 {
+		p := r.Intn(len(s) - 8)
+		s = s[:p] + "ORIGINAL" + s[p+8:]
+	}
+	return s
+}
+
+// address is the street_1, street_2, city, state and zip.
+func address(r *rand.Rand) string {
+	return fmt.Sprintf("'%s','%s','%s','%s','%s11111'", aString(r, 10, 20), aString(r, 10, 20), aString(r, 10, 20),
+		strings.ToUpper(aString(r, 2, 2)), nString(r, 4, 4))
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
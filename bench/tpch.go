@@ -0,0 +1,514 @@
+package bench
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"math/rand"
+	"pictorial/log"
+	"pictorial/mysql"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed "resource/tpch.sql"
+var tpchSQL string
+
+// the rows of the tables at scale 1, lineitem has 1 to 7 rows of each order.
+const (
+	tpchSuppliers = 10000
+	tpchParts     = 200000
+	tpchCustomers = 150000
+	tpchOrders    = 1500000
+	// tpchTimeout bounds each statement, the queries of a large scale run for minutes.
+	tpchTimeout = 10 * time.Minute
+)
+
+var tpchTables = []string{"lineitem", "orders", "customer", "partsupp", "part", "supplier", "nation", "region"}
+
+var tpchSchema = []string{
+	`CREATE TABLE IF NOT EXISTS %s.region (r_regionkey BIGINT NOT NULL, r_name CHAR(25) NOT NULL, r_comment VARCHAR(152),
+	PRIMARY KEY (r_regionkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.nation (n_nationkey BIGINT NOT NULL, n_name CHAR(25) NOT NULL, n_regionkey BIGINT NOT NULL,
+	n_comment VARCHAR(152), PRIMARY KEY (n_nationkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.supplier (s_suppkey BIGINT NOT NULL, s_name CHAR(25) NOT NULL, s_address VARCHAR(40) NOT NULL,
+	s_nationkey BIGINT NOT NULL, s_phone CHAR(15) NOT NULL, s_acctbal DECIMAL(15,2) NOT NULL, s_comment VARCHAR(101) NOT NULL,
+	PRIMARY KEY (s_suppkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.part (p_partkey BIGINT NOT NULL, p_name VARCHAR(55) NOT NULL, p_mfgr CHAR(25) NOT NULL,
+	p_brand CHAR(10) NOT NULL, p_type VARCHAR(25) NOT NULL, p_size BIGINT NOT NULL, p_container CHAR(10) NOT NULL,
+	p_retailprice DECIMAL(15,2) NOT NULL, p_comment VARCHAR(23) NOT NULL, PRIMARY KEY (p_partkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.partsupp (ps_partkey BIGINT NOT NULL, ps_suppkey BIGINT NOT NULL, ps_availqty BIGINT NOT NULL,
+	ps_supplycost DECIMAL(15,2) NOT NULL, ps_comment VARCHAR(199) NOT NULL, PRIMARY KEY (ps_partkey, ps_suppkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.customer (c_custkey BIGINT NOT NULL, c_name VARCHAR(25) NOT NULL, c_address VARCHAR(40) NOT NULL,
+	c_nationkey BIGINT NOT NULL, c_phone CHAR(15) NOT NULL, c_acctbal DECIMAL(15,2) NOT NULL, c_mktsegment CHAR(10) NOT NULL,
+	c_comment VARCHAR(117) NOT NULL, PRIMARY KEY (c_custkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.orders (o_orderkey BIGINT NOT NULL, o_custkey BIGINT NOT NULL, o_orderstatus CHAR(1) NOT NULL,
+	o_totalprice DECIMAL(15,2) NOT NULL, o_orderdate DATE NOT NULL, o_orderpriority CHAR(15) NOT NULL, o_clerk CHAR(15) NOT NULL,
+	o_shippriority BIGINT NOT NULL, o_comment VARCHAR(79) NOT NULL, PRIMARY KEY (o_orderkey))`,
+	`CREATE TABLE IF NOT EXISTS %s.lineitem (l_orderkey BIGINT NOT NULL, l_partkey BIGINT NOT NULL, l_suppkey BIGINT NOT NULL,
+	l_linenumber BIGINT NOT NULL, l_quantity DECIMAL(15,2) NOT NULL, l_extendedprice DECIMAL(15,2) NOT NULL,
+	l_discount DECIMAL(15,2) NOT NULL, l_tax DECIMAL(15,2) NOT NULL, l_returnflag CHAR(1) NOT NULL, l_linestatus CHAR(1) NOT NULL,
+	l_shipdate DATE NOT NULL, l_commitdate DATE NOT NULL, l_receiptdate DATE NOT NULL, l_shipinstruct CHAR(25) NOT NULL,
+	l_shipmode CHAR(10) NOT NULL, l_comment VARCHAR(44) NOT NULL, PRIMARY KEY (l_orderkey, l_linenumber))`,
+}
+
+var (
+	tpchRegions = []string{"AFRICA", "AMERICA", "ASIA", "EUROPE", "MIDDLE EAST"}
+	// tpchNations are the names and the regions of the nations.
+	tpchNations = []struct {
+		name   string
+		region int
+	}{
+		{"ALGERIA", 0}, {"ARGENTINA", 1}, {"BRAZIL", 1}, {"CANADA", 1}, {"EGYPT", 4}, {"ETHIOPIA", 0}, {"FRANCE", 3},
+		{"GERMANY", 3}, {"INDIA", 2}, {"INDONESIA", 2}, {"IRAN", 4}, {"IRAQ", 4}, {"JAPAN", 2}, {"JORDAN", 4}, {"KENYA", 0},
+		{"MOROCCO", 0}, {"MOZAMBIQUE", 0}, {"PERU", 1}, {"CHINA", 2}, {"ROMANIA", 3}, {"SAUDI ARABIA", 4}, {"VIETNAM", 2},
+		{"RUSSIA", 3}, {"UNITED KINGDOM", 3}, {"UNITED STATES", 1},
+	}
+	tpchColors = []string{"almond", "antique", "aquamarine", "azure", "beige", "bisque", "black", "blanched", "blue", "blush",
+		"brown", "burlywood", "burnished", "chartreuse", "chiffon", "chocolate", "coral", "cornflower", "cornsilk", "cream",
+		"cyan", "dark", "deep", "dim", "dodger", "drab", "firebrick", "floral", "forest", "frosted", "gainsboro", "ghost",
+		"goldenrod", "green", "grey", "honeydew", "hot", "indian", "ivory", "khaki", "lace", "lavender", "lawn", "lemon",
+		"light", "lime", "linen", "magenta", "maroon", "medium", "metallic", "midnight", "mint", "misty", "moccasin", "navajo",
+		"navy", "olive", "orange", "orchid", "pale", "papaya", "peach", "peru", "pink", "plum", "powder", "puff", "purple",
+		"red", "rose", "rosy", "royal", "saddle", "salmon", "sandy", "seashell", "sienna", "sky", "slate", "smoke", "snow",
+		"spring", "steel", "tan", "thistle", "tomato", "turquoise", "violet", "wheat", "white", "yellow"}
+	tpchTypes      = [][]string{{"STANDARD", "SMALL", "MEDIUM", "LARGE", "ECONOMY", "PROMO"}, {"ANODIZED", "BURNISHED", "PLATED", "POLISHED", "BRUSHED"}, {"TIN", "NICKEL", "BRASS", "STEEL", "COPPER"}}
+	tpchContainers = [][]string{{"SM", "LG", "MED", "JUMBO", "WRAP"}, {"CASE", "BOX", "BAG", "JAR", "PKG", "PACK", "CAN", "DRUM"}}
+	tpchSegments   = []string{"AUTOMOBILE", "BUILDING", "FURNITURE", "MACHINERY", "HOUSEHOLD"}
+	tpchPriorities = []string{"1-URGENT", "2-HIGH", "3-MEDIUM", "4-NOT SPECIFIED", "5-LOW"}
+	tpchInstructs  = []string{"DELIVER IN PERSON", "COLLECT COD", "NONE", "TAKE BACK RETURN"}
+	tpchModes      = []string{"REG AIR", "AIR", "RAIL", "SHIP", "TRUCK", "MAIL", "FOB"}
+	// tpchWords make the comments, some of the queries look for the special requests and the customer complaints.
+	tpchWords = []string{"furiously", "quickly", "carefully", "blithely", "slyly", "final", "ironic", "pending", "regular",
+		"express", "special", "bold", "even", "silent", "unusual", "requests", "deposits", "packages", "accounts", "theodolites",
+		"instructions", "foxes", "ideas", "pinto", "beans", "asymptotes", "dependencies", "excuses", "platelets", "sleep",
+		"wake", "haggle", "nag", "use", "boost", "affix", "detect", "integrate", "cajole", "among", "across", "above"}
+)
+
+var (
+	tpchStart   = time.Date(1992, 1, 1, 0, 0, 0, 0, time.UTC)
+	tpchCurrent = time.Date(1995, 6, 17, 0, 0, 0, 0, time.UTC)
+	// tpchDays are the order dates, the last one is 151 days before 1998-12-31.
+	tpchDays = int(time.Date(1998, 8, 2, 0, 0, 0, 0, time.UTC).Sub(tpchStart).Hours() / 24)
+)
+
+// Tpch runs the 22 queries of tpch in tipoc, the cmd is prepare, run or cleanup.
+// The data are generated like dbgen but not byte for byte, the queries are of the validation parameters.
+type Tpch struct {
+	Mysql   mysql.MySQL
+	DB      string
+	Scale   float64
+	Threads int
+	Cmd     string
+	// Queries are the names of the queries to run in turn, e.g. q1, all of them if empty.
+	Queries []string
+	// Duration is the time of run, it runs until the context is done if 0.
+	Duration time.Duration
+}
+
+func (t *Tpch) String() string {
+	s := fmt.Sprintf("tpch %s --db=%s --sf=%g --threads=%d", t.cmd(), t.DB, t.scale(), t.threads())
+	if len(t.Queries) != 0 {
+		s += " --queries=" + strings.Join(t.Queries, ",")
+	}
+	if t.Duration != 0 {
+		s += fmt.Sprintf(" --time=%s", t.Duration)
+	}
+	return s
+}
+
+func (t *Tpch) cmd() string {
+	if t.Cmd == "" {
+		return CmdRun
+	}
+	return t.Cmd
+}
+
+func (t *Tpch) scale() float64 {
+	if t.Scale <= 0 {
+		return 1
+	}
+	return t.Scale
+}
+
+func (t *Tpch) threads() int {
+	if t.Threads <= 0 {
+		return 1
+	}
+	return t.Threads
+}
+
+func (t *Tpch) rows(n int) int {
+	if r := int(float64(n) * t.scale()); r > 0 {
+		return r
+	}
+	return 1
+}
+
+func (t *Tpch) table(name string) string {
+	return t.DB + "." + name
+}
+
+func (t *Tpch) session() *mysql.Session {
+	s, _ := t.Mysql.NewSessionWithTimeout(t.Mysql.User, t.Mysql.Password, tpchTimeout)
+	return s
+}
+
+// Exec runs the cmd, run prepares the data first if they are not loaded.
+func (t *Tpch) Exec(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	switch t.cmd() {
+	case CmdPrepare:
+		return nil, t.Prepare(ctx)
+	case CmdCleanup:
+		return nil, t.Cleanup()
+	case CmdRun:
+		if err := t.Prepare(ctx); err != nil {
+			return nil, err
+		}
+		return t.Run(ctx, w, report)
+	}
+	return nil, fmt.Errorf("%s is not supported by tpch", t.cmd())
+}
+
+// Cleanup drops the tables.
+func (t *Tpch) Cleanup() error {
+	s := t.session()
+	defer s.Close()
+	for _, tbl := range tpchTables {
+		if r := s.Execute("DROP TABLE IF EXISTS " + t.table(tbl)); r.Failed() {
+			return fmt.Errorf("[tpch] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// Prepare creates the tables and loads them, region is inserted at last,
+// so the data are truncated and loaded again if region is empty.
+func (t *Tpch) Prepare(ctx context.Context) error {
+	s := t.session()
+	defer s.Close()
+	stmts := []string{fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", t.DB)}
+	for _, ddl := range tpchSchema {
+		stmts = append(stmts, fmt.Sprintf(ddl, t.DB))
+	}
+	for _, stmt := range stmts {
+		if r := s.Execute(stmt); r.Failed() {
+			return fmt.Errorf("[tpch] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	r := s.Execute(fmt.Sprintf("SELECT r_regionkey FROM %s LIMIT 1", t.table("region")))
+	if r.Failed() {
+		return fmt.Errorf("[tpch] %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	if len(r.Rows) != 0 {
+		log.Logger.Infof("[tpch] sf %g is loaded.", t.scale())
+		return nil
+	}
+	for _, tbl := range tpchTables {
+		if r := s.Execute("TRUNCATE TABLE " + t.table(tbl)); r.Failed() {
+			return fmt.Errorf("[tpch] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+
+	// the tables are split into chunks of tpccBatch rows, which are inserted in parallel
+	type chunk struct {
+		table    string
+		from, to int
+		row      func(r *rand.Rand, i int) string
+	}
+	var chunks []chunk
+	split := func(tbl string, n int, row func(r *rand.Rand, i int) string) {
+		log.Logger.Infof("[tpch] load %d rows into %s", n, tbl)
+		for from := 1; from <= n; from += tpccBatch {
+			to := from + tpccBatch - 1
+			if to > n {
+				to = n
+			}
+			chunks = append(chunks, chunk{tbl, from, to, row})
+		}
+	}
+	suppliers := t.rows(tpchSuppliers)
+	if suppliers < 4 {
+		suppliers = 4
+	}
+	parts := t.rows(tpchParts)
+	customers := t.rows(tpchCustomers)
+	split("supplier", suppliers, func(r *rand.Rand, i int) string {
+		n := r.Intn(len(tpchNations))
+		comment := tpchComment(r, 25, 100)
+		if r.Intn(2000) == 0 {
+			comment = "Customer Complaints " + comment[:len(comment)/2]
+		}
+		return fmt.Sprintf("(%d,'Supplier#%09d','%s',%d,'%s',%.2f,'%s')", i, i, aString(r, 10, 40), n, tpchPhone(r, n),
+			float64(randInt(r, -99999, 999999))/100, comment)
+	})
+	split("part", parts, func(r *rand.Rand, i int) string {
+		perm := r.Perm(len(tpchColors))
+		names := make([]string, 5)
+		for j := range names {
+			names[j] = tpchColors[perm[j]]
+		}
+		m := randInt(r, 1, 5)
+		return fmt.Sprintf("(%d,'%s','Manufacturer#%d','Brand#%d%d','%s',%d,'%s',%.2f,'%s')", i, strings.Join(names, " "), m,
+			m, randInt(r, 1, 5), tpchPick(r, tpchTypes...), randInt(r, 1, 50), tpchPick(r, tpchContainers...),
+			tpchPrice(i), tpchComment(r, 5, 22))
+	})
+	split("partsupp", parts*4, func(r *rand.Rand, i int) string {
+		p, j := (i-1)/4+1, (i-1)%4
+		return fmt.Sprintf("(%d,%d,%d,%.2f,'%s')", p, tpchSupplier(p, j, suppliers), randInt(r, 1, 9999),
+			float64(randInt(r, 100, 100000))/100, tpchComment(r, 49, 198))
+	})
+	split("customer", customers, func(r *rand.Rand, i int) string {
+		n := r.Intn(len(tpchNations))
+		return fmt.Sprintf("(%d,'Customer#%09d','%s',%d,'%s',%.2f,'%s','%s')", i, i, aString(r, 10, 40), n, tpchPhone(r, n),
+			float64(randInt(r, -99999, 999999))/100, tpchSegments[r.Intn(len(tpchSegments))], tpchComment(r, 29, 116))
+	})
+	// an order and its lines are a row of the chunk, the row is the values of both separated by ';'
+	split("orders", t.rows(tpchOrders), func(r *rand.Rand, i int) string {
+		return t.order(r, i, customers, parts, suppliers)
+	})
+
+	chunkC := make(chan chunk)
+	errC := make(chan error, t.threads())
+	var wg sync.WaitGroup
+	for i := 0; i < t.threads(); i++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			s := t.session()
+			defer s.Close()
+			for c := range chunkC {
+				values := make([]string, 0, c.to-c.from+1)
+				var lines []string
+				for i := c.from; i <= c.to; i++ {
+					v := c.row(r, i)
+					if c.table == "orders" {
+						parts := strings.SplitN(v, ";", 2)
+						v = parts[0]
+						lines = append(lines, parts[1])
+					}
+					values = append(values, v)
+				}
+				stmts := []string{fmt.Sprintf("INSERT INTO %s VALUES %s", t.table(c.table), strings.Join(values, ","))}
+				if len(lines) != 0 {
+					stmts = append(stmts, fmt.Sprintf("INSERT INTO %s VALUES %s", t.table("lineitem"), strings.Join(lines, ",")))
+				}
+				for _, stmt := range stmts {
+					if res := s.Execute(stmt); res.Failed() {
+						errC <- fmt.Errorf("[tpch] insert into %s failed, %d: %s", c.table, res.ErrCode, res.ErrMsg)
+						return
+					}
+				}
+			}
+		}(newRand(int64(i)))
+	}
+	var err error
+loop:
+	for _, c := range chunks {
+		select {
+		case chunkC <- c:
+		case err = <-errC:
+			break loop
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		}
+	}
+	close(chunkC)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errC:
+		default:
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var nations, regions []string
+	for i, n := range tpchNations {
+		nations = append(nations, fmt.Sprintf("(%d,'%s',%d,'%s')", i, n.name, n.region, tpchComment(newRand(int64(i)), 31, 114)))
+	}
+	for i, name := range tpchRegions {
+		regions = append(regions, fmt.Sprintf("(%d,'%s','%s')", i, name, tpchComment(newRand(int64(i)), 31, 115)))
+	}
+	for _, stmt := range []string{
+		fmt.Sprintf("INSERT INTO %s VALUES %s", t.table("nation"), strings.Join(nations, ",")),
+		fmt.Sprintf("INSERT INTO %s VALUES %s", t.table("region"), strings.Join(regions, ",")),
+	} {
+		if r := s.Execute(stmt); r.Failed() {
+			return fmt.Errorf("[tpch] %d: %s", r.ErrCode, r.ErrMsg)
+		}
+	}
+	log.Logger.Infof("[tpch] sf %g is loaded.", t.scale())
+	return nil
+}
+
+// order is the values of the order i and its lines, the keys of the orders are sparse like dbgen.
+func (t *Tpch) order(r *rand.Rand, i, customers, parts, suppliers int) string {
+	key := (i-1)/8*32 + (i-1)%8 + 1
+	// a third of the customers have no orders
+	c := randInt(r, 1, customers)
+	for customers >= 3 && c%3 == 0 {
+		c = randInt(r, 1, customers)
+	}
+	date := tpchStart.AddDate(0, 0, r.Intn(tpchDays))
+	var lines []string
+	var total float64
+	shipped := 0
+	cnt := randInt(r, 1, 7)
+	for n := 1; n <= cnt; n++ {
+		p := randInt(r, 1, parts)
+		q := randInt(r, 1, 50)
+		price := tpchPrice(p) * float64(q)
+		discount := float64(randInt(r, 0, 10)) / 100
+		tax := float64(randInt(r, 0, 8)) / 100
+		total += price * (1 + tax) * (1 - discount)
+		ship := date.AddDate(0, 0, randInt(r, 1, 121))
+		commit := date.AddDate(0, 0, randInt(r, 30, 90))
+		receipt := ship.AddDate(0, 0, randInt(r, 1, 30))
+		flag, status := "N", "O"
+		if !receipt.After(tpchCurrent) {
+			flag = []string{"R", "A"}[r.Intn(2)]
+		}
+		if !ship.After(tpchCurrent) {
+			status = "F"
+			shipped++
+		}
+		lines = append(lines, fmt.Sprintf("(%d,%d,%d,%d,%d,%.2f,%.2f,%.2f,'%s','%s','%s','%s','%s','%s','%s','%s')",
+			key, p, tpchSupplier(p, r.Intn(4), suppliers), n, q, price, discount, tax, flag, status,
+			tpchDate(ship), tpchDate(commit), tpchDate(receipt), tpchInstructs[r.Intn(len(tpchInstructs))],
+			tpchModes[r.Intn(len(tpchModes))], tpchComment(r, 10, 43)))
+	}
+	status := "P"
+	switch shipped {
+	case cnt:
+		status = "F"
+	case 0:
+		status = "O"
+	}
+	comment := tpchComment(r, 19, 78)
+	if r.Intn(100) == 0 {
+		comment = "special " + comment[:len(comment)/2] + " requests"
+	}
+	return fmt.Sprintf("(%d,%d,'%s',%.2f,'%s','%s','Clerk#%09d',0,'%s');%s", key, c, status, total, tpchDate(date),
+		tpchPriorities[r.Intn(len(tpchPriorities))], randInt(r, 1, t.rows(1000)), comment, strings.Join(lines, ","))
+}
+
+// Run runs the queries in turn on each thread until the duration or the context is done,
+// the summary has the latency of each query.
+func (t *Tpch) Run(ctx context.Context, w io.Writer, report func(Sample)) (*Summary, error) {
+	queries, err := t.queries()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(queries))
+	for i, q := range queries {
+		names[i] = q.name
+	}
+	m := newMeter(-1, names...)
+	drive(ctx, t.Duration, t.threads(), m, w, report, func(ctx context.Context, n int) {
+		s := t.session()
+		defer s.Close()
+		for i := n; ctx.Err() == nil; i++ {
+			q := i % len(queries)
+			start := time.Now()
+			// the session loses the database after reconnecting, so it is used before each query
+			res := s.Execute("USE " + t.DB)
+			if !res.Failed() {
+				res = s.Execute(queries[q].sql)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if res.Failed() {
+				fmt.Fprintf(w, "[tpch] %s failed, %d: %s\n", queries[q].name, res.ErrCode, res.ErrMsg)
+				m.add(q, time.Since(start), 1, fmt.Errorf("%d: %s", res.ErrCode, res.ErrMsg))
+				if res.ConnectionLost() {
+					time.Sleep(errorBackoff)
+				}
+				continue
+			}
+			m.add(q, time.Since(start), 1, nil)
+		}
+	})
+	sum, hist := m.summary()
+	sum.write(w, "tpch")
+	hist.Write(w)
+	return sum, nil
+}
+
+type tpchQuery struct {
+	name string
+	sql  string
+}
+
+// queries splits the embedded sql by the "-- qN" lines and selects the Queries.
+func (t *Tpch) queries() ([]tpchQuery, error) {
+	var all []tpchQuery
+	for _, part := range strings.Split(tpchSQL, "-- ")[1:] {
+		lines := strings.SplitN(part, "\n", 2)
+		all = append(all, tpchQuery{
+			name: strings.TrimSpace(lines[0]),
+			sql:  strings.TrimSuffix(strings.TrimSpace(lines[1]), ";"),
+		})
+	}
+	if len(t.Queries) == 0 {
+		return all, nil
+	}
+	var selected []tpchQuery
+	for _, name := range t.Queries {
+		found := false
+		for _, q := range all {
+			if q.name == strings.ToLower(name) {
+				selected = append(selected, q)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("tpch has no query %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// tpchSupplier is the j-th of the 4 suppliers of the part p, there are 4 suppliers at least.
+func tpchSupplier(p, j, suppliers int) int {
+	return (p+j*(suppliers/4))%suppliers + 1
+}
+
+func tpchPrice(p int) float64 {
+	return float64(90000+(p/10)%20001+100*(p%1000)) / 100
+}
+
+func tpchPhone(r *rand.Rand, nation int) string {
+	return fmt.Sprintf("%d-%d-%d-%d", nation+10, randInt(r, 100, 999), randInt(r, 100, 999), randInt(r, 1000, 9999))
+}
+
+func tpchPick(r *rand.Rand, syllables ...[]string) string {
+	words := make([]string, len(syllables))
+	for i, s := range syllables {
+		words[i] = s[r.Intn(len(s))]
+	}
+	return strings.Join(words, " ")
+}
+
+func tpchComment(r *rand.Rand, min, max int) string {
+	n := randInt(r, min, max)
+	var sb strings.Builder
+	for sb.Len() < n {
+		if sb.Len() != 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tpchWords[r.Intn(len(tpchWords))])
+	}
+	return sb.String()[:n]
+}
+
+func tpchDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
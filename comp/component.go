@@ -1,8 +1,12 @@
 package comp
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"pictorial/log"
 	"pictorial/ssh"
+	"strconv"
 	"strings"
 )
 
@@ -13,7 +17,9 @@ const topologyGrafana = "/topology/grafana"
 const membersUrl = "http://%s/pd/api/v1/members"
 const pdConfigUrl = "http://%s/pd/api/v1/config"
 const storeUrl = "http://%s/pd/api/v1/stores"
+const pdHealthUrl = "http://%s/pd/api/v1/health"
 const tidbAllInfoUrl = "http://%s/info/all"
+const tidbStatusUrl = "http://%s/status"
 
 const Leader = "(L)"
 
@@ -26,6 +32,7 @@ const (
 	TiKV
 	TiFlash
 	Grafana
+	Prometheus
 )
 
 func GetCTypeValue(c CType) string {
@@ -40,6 +47,8 @@ func GetCTypeValue(c CType) string {
 		return "tiflash"
 	case Grafana:
 		return "grafana"
+	case Prometheus:
+		return "prometheus"
 	default:
 		return ""
 	}
@@ -53,6 +62,7 @@ type Component struct {
 	Host       string
 	Port       string
 	DeployPath string
+	StatusPort string
 	Labels     map[string]string
 	Status     string
 }
@@ -70,12 +80,55 @@ func New() (*Mapping, error) {
 	if err := m.GetStore(); err != nil {
 		return nil, err
 	}
-	if err := m.GetGrafana(); err != nil {
+	if err := m.GetGrafana(); errors.Is(err, ErrNoGrafana) {
+		log.Logger.Warn(err)
+	} else if err != nil {
 		return nil, err
 	}
+	if err := m.GetPrometheus(); err != nil {
+		return nil, err
+	}
+	if ssh.S.Topology != nil {
+		m.complete(ssh.S.Topology)
+	}
 	return &m, nil
 }
 
+// complete fills what the cluster does not report from the topology, the grafana and the prometheus which are not
+// registered in pd, e.g. a cluster not deployed by tiup, and the empty deploy dirs.
+func (m *Mapping) complete(t *ssh.Topology) {
+	for _, cType := range []CType{Grafana, Prometheus} {
+		if len(m.Map[cType]) != 0 {
+			continue
+		}
+		for _, i := range t.Instances {
+			if i.Role == GetCTypeValue(cType) {
+				m.Map[cType] = append(m.Map[cType], Component{
+					Host:       i.Host,
+					Port:       strconv.Itoa(i.Port),
+					DeployPath: i.DeployDir,
+				})
+			}
+		}
+	}
+	for cType, cs := range m.Map {
+		for n := range cs {
+			c := &cs[n]
+			port, err := strconv.Atoi(CleanLeaderFlag(c.Port))
+			if c.DeployPath != "" || err != nil {
+				continue
+			}
+			if i, ok := t.Find(GetCTypeValue(cType), c.Host, port); ok {
+				c.DeployPath = i.DeployDir
+				// pd and the stores report the dir of the binary
+				if cType != TiDB && cType != Grafana && cType != Prometheus {
+					c.DeployPath = filepath.Join(c.DeployPath, "bin")
+				}
+			}
+		}
+	}
+}
+
 func (m *Mapping) GetComponent(c CType) []Component {
 	return m.Map[c]
 }
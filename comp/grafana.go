@@ -3,10 +3,11 @@ package comp
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"gopkg.in/yaml.v2"
 	"io"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"pictorial/etcd"
@@ -28,11 +29,17 @@ type G struct {
 //go:embed "resource/*"
 var RenderPlugin embed.FS
 
+// ErrNoGrafana is returned by GetGrafana if the cluster is deployed without grafana.
+var ErrNoGrafana = errors.New("grafana is not found in the topology")
+
 func (m *Mapping) GetGrafana() error {
 	rs, err := etcd.GetByPrefix(PdAddr, topologyGrafana)
 	if err != nil {
 		return err
 	}
+	if len(rs.Kvs) == 0 {
+		return ErrNoGrafana
+	}
 	var g *G
 	if err := json.Unmarshal(rs.Kvs[0].Value, &g); err != nil {
 		return err
@@ -93,9 +100,8 @@ func (c *Component) installPlugin() error {
 	if err := file.UnzipPackage(mergeZip.Name(), "./"); err != nil {
 		return err
 	}
-	target := fmt.Sprintf("%s@%s:%s", s.User, c.Host, pluginPath)
-	log.Logger.Infof("%s -> %s", pluginName, target)
-	if _, err := s.TransferR(pluginName, target); err != nil {
+	log.Logger.Infof("%s -> %s:%s", pluginName, c.Host, pluginPath)
+	if _, err := s.Upload(c.Host, pluginName, pluginPath); err != nil {
 		return err
 	}
 	if err := c.dependencies(); err != nil {
@@ -104,7 +110,7 @@ func (c *Component) installPlugin() error {
 	return nil
 }
 
-func (c *Component) Render(to string, oType string) error {
+func (c *Component) Render(to, set string, start time.Time) error {
 	log.Logger.Info("start grafana image render...")
 	if err := c.installPlugin(); err != nil {
 		return err
@@ -113,21 +119,36 @@ func (c *Component) Render(to string, oType string) error {
 	if err != nil {
 		return err
 	}
-	now, from := unixDuration()
-	pls, err := getPanels(oType)
+	from, now := timeWindow(start)
+	pls, err := getPanels(set)
+	if err != nil {
+		return err
+	}
+	orgID, err := c.orgID(tok.Key)
 	if err != nil {
 		return err
 	}
 	s := ssh.S
-	uri := "http://%s:%s/render/d-solo/%s/%s-%s?orgId=1&from=%s&to=%s&panelId=%s&width=1000&height=500&scale=3"
+	uri := "http://%s:%s/render/d-solo/%s/%s?orgId=%d&from=%d&to=%d&panelId=%s&width=1000&height=500&scale=3"
 	source := filepath.Join(c.DeployPath, "data", "png", "*")
 	dataPath := filepath.Join(c.DeployPath, "data", "png")
 	if _, err := s.Remove(c.Host, source); err != nil {
 		return err
 	}
 	time.Sleep(3 * time.Second)
+	dashboards := make(map[string]*dashboard)
 	for _, p := range pls {
-		cmd := fmt.Sprintf(uri, c.Host, c.Port, p.Org, s.Cluster.Name, p.Tab, from, now, p.ID) + "&tz=Asia%2FShanghai"
+		d, ok := dashboards[p.Dashboard]
+		if !ok {
+			if d, err = c.searchDashboard(tok.Key, fmt.Sprintf("%s-%s", s.Cluster.Name, p.Dashboard)); err != nil {
+				return err
+			}
+			dashboards[p.Dashboard] = d
+		}
+		cmd := fmt.Sprintf(uri, c.Host, c.Port, d.UID, d.slug(), orgID, from.UnixMilli(), now.UnixMilli(), p.ID)
+		if Metrics.TZ != "" {
+			cmd += "&tz=" + url.QueryEscape(Metrics.TZ)
+		}
 		kv := map[string]string{
 			"Authorization": fmt.Sprintf("Bearer %s", tok.Key),
 		}
@@ -142,11 +163,8 @@ func (c *Component) Render(to string, oType string) error {
 		if len(out) == 0 {
 			return fmt.Errorf("render failed, 'grep 'eror' %s/log/grafana.log', skip: %s", p.Name, c.DeployPath)
 		}
-		sourcePath := fmt.Sprintf("%s@%s:%s", s.User, c.Host, source)
-		if _, err = s.Mv(c.Host, source+".png", filepath.Join(dataPath, fmt.Sprintf("%s.png", p.Name))); err != nil {
-			return err
-		}
-		if _, err = s.Transfer(sourcePath, to); err != nil {
+		// the png of the panel is the only one in the dir, it is named after the panel by the download.
+		if _, err = s.Download(c.Host, source+".png", filepath.Join(to, fmt.Sprintf("%s.png", p.Name))); err != nil {
 			return err
 		}
 		if _, err = s.Remove(c.Host, source); err != nil {
@@ -157,6 +175,57 @@ func (c *Component) Render(to string, oType string) error {
 	return c.cleanToken(tok.Key)
 }
 
+type dashboard struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// slug is the last part of the url, e.g. /d/000000011/tidb-test-tidb.
+func (d *dashboard) slug() string {
+	return d.URL[strings.LastIndex(d.URL, "/")+1:]
+}
+
+// searchDashboard looks up the dashboard by the title, the uid differs between the deployments.
+func (c *Component) searchDashboard(key, title string) (*dashboard, error) {
+	u := fmt.Sprintf("http://%s:%s/api/search?type=dash-db&query=%s", c.Host, c.Port, url.QueryEscape(title))
+	kv := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", key),
+	}
+	out, err := http.NewRequestDo(u, http.MethodGet, nil, kv, "")
+	if err != nil {
+		return nil, err
+	}
+	var ds []dashboard
+	if err := json.Unmarshal(out, &ds); err != nil {
+		return nil, fmt.Errorf("search dashboard %s failed: %v: %s", title, err, string(out))
+	}
+	for i := range ds {
+		if strings.EqualFold(ds[i].Title, title) {
+			return &ds[i], nil
+		}
+	}
+	return nil, fmt.Errorf("dashboard %s is not found", title)
+}
+
+func (c *Component) orgID(key string) (int, error) {
+	u := fmt.Sprintf("http://%s:%s/api/org", c.Host, c.Port)
+	kv := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", key),
+	}
+	out, err := http.NewRequestDo(u, http.MethodGet, nil, kv, "")
+	if err != nil {
+		return 0, err
+	}
+	var org struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(out, &org); err != nil {
+		return 0, fmt.Errorf("get org failed: %v: %s", err, string(out))
+	}
+	return org.ID, nil
+}
+
 type token struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -226,15 +295,6 @@ func (c *Component) dropToken(id string) error {
 	return nil
 }
 
-func unixDuration() (string, string) {
-	t := time.Now()
-	now := t.UnixNano() / 1000000
-	ot := t.Add(-30 * time.Minute)
-	from := ot.UnixNano() / 1000000
-	log.Logger.Infof("time_horizon: %s ~ %s", timeFormat(ot), timeFormat(t))
-	return strconv.Itoa(int(now)), strconv.Itoa(int(from))
-}
-
 func timeFormat(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05.000")
 }
@@ -253,54 +313,3 @@ func (c *Component) dependencies() error {
 	}
 	return nil
 }
-
-//go:embed "panel.yaml"
-var panelPath embed.FS
-
-const panelYaml = "panel.yaml"
-
-type panel struct {
-	ID   string
-	Tab  string
-	Name string
-	Org  string
-}
-
-func getPanels(oType string) (map[string]panel, error) {
-	p, err := panelPath.ReadFile(panelYaml)
-	if err != nil {
-		return nil, err
-	}
-	pls := make(map[string]panel)
-	if err = yaml.Unmarshal(p, &pls); err != nil {
-		return nil, err
-	}
-	newPls := make(map[string]panel)
-	switch oType {
-	case "data_distribution":
-		newPls["region"] = getTargetPanel(pls, "region")
-		newPls["store_size"] = getTargetPanel(pls, "store_size")
-		newPls["leader"] = getTargetPanel(pls, "leader")
-	case "disk_full":
-		newPls["io_util"] = getTargetPanel(pls, "io_util")
-		newPls["duration"] = getTargetPanel(pls, "duration")
-		newPls["qps"] = getTargetPanel(pls, "qps")
-		newPls["pd_uptime"] = getTargetPanel(pls, "pd_uptime")
-		newPls["tikv_uptime"] = getTargetPanel(pls, "tikv_uptime")
-	case "online_ddl_add_index", "online_add_modify_column":
-		newPls["duration"] = getTargetPanel(pls, "duration")
-		newPls["qps"] = getTargetPanel(pls, "qps")
-		newPls["ddl_duration"] = getTargetPanel(pls, "ddl_duration")
-	default:
-		newPls["duration"] = getTargetPanel(pls, "duration")
-		newPls["qps"] = getTargetPanel(pls, "qps")
-		newPls["tidb_uptime"] = getTargetPanel(pls, "tidb_uptime")
-		newPls["pd_uptime"] = getTargetPanel(pls, "pd_uptime")
-		newPls["tikv_uptime"] = getTargetPanel(pls, "tikv_uptime")
-	}
-	return newPls, nil
-}
-
-func getTargetPanel(m map[string]panel, k string) panel {
-	return m[k]
-}
@@ -0,0 +1,90 @@
+package comp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"pictorial/util/http"
+	"time"
+)
+
+// storeHeartbeatInterval is the default heartbeat interval of tikv and tiflash.
+const storeHeartbeatInterval = 10 * time.Second
+
+type memberHealth struct {
+	Name       string   `json:"name"`
+	ClientURLs []string `json:"client_urls"`
+	Health     bool     `json:"health"`
+}
+
+// Unhealthy returns the unhealthy components of the cluster, it is healthy if empty.
+// A store should be up and heartbeat after since, so a store killed after since is not counted as healthy
+// before pd notices it.
+func (m *Mapping) Unhealthy(since time.Time) []string {
+	var r []string
+	r = append(r, m.unhealthyPD()...)
+	r = append(r, m.unhealthyStore(since)...)
+	r = append(r, m.unhealthyTiDB()...)
+	return r
+}
+
+func (m *Mapping) unhealthyPD() []string {
+	resp, err := http.Get(fmt.Sprintf(pdHealthUrl, PdAddr))
+	if err != nil {
+		return []string{fmt.Sprintf("pd %s: %s", PdAddr, err.Error())}
+	}
+	var hs []memberHealth
+	if err := json.Unmarshal(resp, &hs); err != nil {
+		return []string{fmt.Sprintf("pd %s is not serving", PdAddr)}
+	}
+	healthy := make(map[string]bool)
+	for _, h := range hs {
+		for _, u := range h.ClientURLs {
+			healthy[http.ClearHttpHeader(u)] = h.Health
+		}
+	}
+	var r []string
+	for _, pd := range m.Map[PD] {
+		addr := net.JoinHostPort(pd.Host, CleanLeaderFlag(pd.Port))
+		if !healthy[addr] {
+			r = append(r, fmt.Sprintf("pd %s is unhealthy", addr))
+		}
+	}
+	return r
+}
+
+func (m *Mapping) unhealthyStore(since time.Time) []string {
+	ss, err := GetStoreStatuses()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var r []string
+	for _, cType := range []CType{TiKV, TiFlash} {
+		for _, c := range m.Map[cType] {
+			addr := net.JoinHostPort(c.Host, c.Port)
+			s, ok := ss[addr]
+			switch {
+			case !ok:
+				r = append(r, fmt.Sprintf("%s %s is not found", GetCTypeValue(cType), addr))
+			case s.StateName != StoreUp:
+				r = append(r, fmt.Sprintf("%s %s is %s", GetCTypeValue(cType), addr, s.StateName))
+			case s.LastHeartbeatTS.Before(since.Add(storeHeartbeatInterval)):
+				r = append(r, fmt.Sprintf("%s %s has no heartbeat since %s", GetCTypeValue(cType), addr, s.LastHeartbeatTS.Format(time.RFC3339)))
+			}
+		}
+	}
+	return r
+}
+
+func (m *Mapping) unhealthyTiDB() []string {
+	var r []string
+	for _, c := range m.Map[TiDB] {
+		if c.StatusPort == "" {
+			continue
+		}
+		if _, err := GetTiDBStatus(net.JoinHostPort(c.Host, c.StatusPort)); err != nil {
+			r = append(r, err.Error())
+		}
+	}
+	return r
+}
@@ -0,0 +1,103 @@
+package comp
+
+import (
+	"embed"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"pictorial/log"
+	"time"
+)
+
+//go:embed "panel.yaml"
+var panelPath embed.FS
+
+const panelYaml = "panel.yaml"
+
+// panel is one graph of the grafana dashboard, the dashboard is looked up by the title "<cluster>-<dashboard>",
+// expr and legend are the prometheus query of the same graph.
+type panel struct {
+	ID        string
+	Dashboard string
+	Name      string
+	Expr      string
+	Legend    []string
+}
+
+// HasPanelSet reports whether the set is declared in the config.
+func HasPanelSet(set string) bool {
+	_, ok := Metrics.Sets[set]
+	return ok
+}
+
+func readPanels() (map[string]panel, error) {
+	var p []byte
+	var err error
+	if Metrics.Panel != "" {
+		p, err = os.ReadFile(Metrics.Panel)
+	} else {
+		p, err = panelPath.ReadFile(panelYaml)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pls := make(map[string]panel)
+	if err = yaml.Unmarshal(p, &pls); err != nil {
+		return nil, err
+	}
+	for k, v := range pls {
+		if v.Name == "" {
+			v.Name = k
+			pls[k] = v
+		}
+	}
+	return pls, nil
+}
+
+func getPanels(set string) ([]panel, error) {
+	pls, err := readPanels()
+	if err != nil {
+		return nil, err
+	}
+	names, ok := Metrics.Sets[set]
+	if !ok {
+		names = panelNames(set)
+	}
+	var r []panel
+	for _, name := range names {
+		p, ok := pls[name]
+		if !ok {
+			return nil, fmt.Errorf("panel %s of %s is not in the panel file", name, set)
+		}
+		r = append(r, p)
+	}
+	return r, nil
+}
+
+// panelNames are the built-in panels rendered after the job of the oType.
+func panelNames(oType string) []string {
+	switch oType {
+	case "data_distribution":
+		return []string{"region", "store_size", "leader"}
+	case "scale_out":
+		return []string{"region", "leader", "qps", "duration"}
+	case "disk_full":
+		return []string{"io_util", "duration", "qps", "pd_uptime", "tikv_uptime"}
+	case "online_ddl_add_index", "online_ddl_modify_column":
+		return []string{"duration", "qps", "ddl_duration"}
+	default:
+		return []string{"duration", "qps", "tidb_uptime", "pd_uptime", "tikv_uptime"}
+	}
+}
+
+// timeWindow is from the start of the job minus the padding to now, a zero start means the last Metrics.Range.
+func timeWindow(start time.Time) (time.Time, time.Time) {
+	end := time.Now()
+	if start.IsZero() {
+		start = end.Add(-Metrics.Range)
+	} else {
+		start = start.Add(-Metrics.Padding)
+	}
+	log.Logger.Infof("time_horizon: %s ~ %s", timeFormat(start), timeFormat(end))
+	return start, end
+}
@@ -53,6 +53,26 @@ func (m *Mapping) GetPD() error {
 	return nil
 }
 
+// IsPDMember reports whether the client url of a pd member is the addr.
+func IsPDMember(addr string) (bool, error) {
+	resp, err := http.Get(fmt.Sprintf(membersUrl, PdAddr))
+	if err != nil {
+		return false, err
+	}
+	var pd *PlacementDriver
+	if err := json.Unmarshal(resp, &pd); err != nil {
+		return false, err
+	}
+	for _, p := range pd.Members {
+		for _, u := range p.ClientURLs {
+			if http.ClearHttpHeader(u) == addr {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func GetPdAddr() (string, error) {
 	rs, err := mysql.M.ExecuteSQL("SELECT * FROM information_schema.cluster_info WHERE type = 'pd'")
 	if err != nil {
@@ -63,7 +83,7 @@ func GetPdAddr() (string, error) {
 		return "", fmt.Errorf("please confirm that pd node exists in the cluster")
 	}
 	pd := string(rs.Values[0][1].AsString())
-	log.Logger.Debug("pd = %s", pd)
+	log.Logger.Debugf("pd = %s", pd)
 	return pd, nil
 }
 
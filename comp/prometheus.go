@@ -0,0 +1,183 @@
+package comp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"pictorial/etcd"
+	"pictorial/log"
+	"pictorial/util/chart"
+	"pictorial/util/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const topologyPrometheus = "/topology/prometheus"
+
+const promQueryRangeUrl = "http://%s:%s/api/v1/query_range?%s"
+
+const (
+	SourcePrometheus = "prometheus"
+	SourceGrafana    = "grafana"
+)
+
+// MetricsConfig is where the panels are rendered from and the time range of them.
+type MetricsConfig struct {
+	Source string
+	// Range is the window if the start of the job is unknown.
+	Range time.Duration
+	Step  time.Duration
+	// Padding is added before the start of the job to render the metrics before the fault.
+	Padding time.Duration
+	// Panel replaces the embedded panel.yaml if it is not empty.
+	Panel string
+	// TZ is the time zone of the grafana images, e.g. Asia/Shanghai, the grafana default if it is empty.
+	TZ string
+	// Sets are the panels rendered after the case, keyed by the catalog index or the operator, e.g. "1.17" or kill,
+	// the built-in sets are used for the keys not declared.
+	Sets map[string][]string
+}
+
+var Metrics = MetricsConfig{
+	Source:  SourcePrometheus,
+	Range:   30 * time.Minute,
+	Step:    15 * time.Second,
+	Padding: time.Minute,
+	Sets:    map[string][]string{},
+}
+
+// GetPrometheus is optional, the panels are rendered by grafana without it.
+func (m *Mapping) GetPrometheus() error {
+	rs, err := etcd.GetByPrefix(PdAddr, topologyPrometheus)
+	if err != nil {
+		return err
+	}
+	if len(rs.Kvs) == 0 {
+		log.Logger.Warnf("%s is not found, render by grafana", topologyPrometheus)
+		return nil
+	}
+	var g *G
+	if err := json.Unmarshal(rs.Kvs[0].Value, &g); err != nil {
+		return err
+	}
+	c := Component{
+		Host:       g.Host,
+		Port:       strconv.Itoa(g.Port),
+		DeployPath: g.DeployPath,
+	}
+	m.Map[Prometheus] = append(m.Map[Prometheus], c)
+	return nil
+}
+
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]interface{}  `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Capture queries the series of the panels from prometheus since the start, they are saved as json, csv and png.
+func (c *Component) Capture(to, set string, start time.Time) error {
+	pls, err := getPanels(set)
+	if err != nil {
+		return err
+	}
+	start, end := timeWindow(start)
+	for _, p := range pls {
+		if p.Expr == "" {
+			log.Logger.Warnf("[render] %s has no expr, skip", p.Name)
+			continue
+		}
+		params := url.Values{}
+		params.Set("query", p.Expr)
+		params.Set("start", strconv.FormatInt(start.Unix(), 10))
+		params.Set("end", strconv.FormatInt(end.Unix(), 10))
+		params.Set("step", strconv.Itoa(int(Metrics.Step.Seconds())))
+		resp, err := http.Get(fmt.Sprintf(promQueryRangeUrl, c.Host, c.Port, params.Encode()))
+		if err != nil {
+			return err
+		}
+		var r promResponse
+		if err := json.Unmarshal(resp, &r); err != nil {
+			return fmt.Errorf("prometheus %s:%s is not serving: %w", c.Host, c.Port, err)
+		}
+		if r.Status != "success" {
+			return fmt.Errorf("[%s] query failed: %s", p.Name, r.Error)
+		}
+		if err := os.WriteFile(filepath.Join(to, p.Name+".json"), resp, 0644); err != nil {
+			return err
+		}
+		series := r.series(p.Legend)
+		if err := writeSeries(filepath.Join(to, p.Name+".csv"), series); err != nil {
+			return err
+		}
+		if err := chart.SavePNG(filepath.Join(to, p.Name+".png"), p.Name, series); err != nil {
+			return err
+		}
+		log.Logger.Infof("[render] %s", p.Name)
+	}
+	return nil
+}
+
+func (r *promResponse) series(legend []string) []chart.Series {
+	var ss []chart.Series
+	for _, res := range r.Data.Result {
+		var names []string
+		for _, l := range legend {
+			names = append(names, res.Metric[l])
+		}
+		s := chart.Series{
+			Name: strings.Join(names, " "),
+		}
+		if s.Name == "" {
+			s.Name = "value"
+		}
+		for _, v := range res.Values {
+			ts, _ := v[0].(float64)
+			raw, _ := v[1].(string)
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			s.Times = append(s.Times, time.Unix(0, int64(ts*float64(time.Second))))
+			s.Values = append(s.Values, f)
+		}
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Name < ss[j].Name })
+	return ss
+}
+
+func writeSeries(name string, series []chart.Series) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"time", "series", "value"}); err != nil {
+		return err
+	}
+	for _, s := range series {
+		for i, v := range s.Values {
+			if err := w.Write([]string{
+				s.Times[i].Format(time.RFC3339),
+				s.Name,
+				strconv.FormatFloat(v, 'f', -1, 64),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
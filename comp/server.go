@@ -6,12 +6,14 @@ import (
 	"net"
 	"pictorial/etcd"
 	"pictorial/mysql"
+	"pictorial/util/http"
 	"strconv"
 	"strings"
 )
 
 type Server struct {
 	DeployPath string `json:"deploy_path"`
+	StatusPort int    `json:"status_port"`
 }
 
 func (m *Mapping) GetServer() error {
@@ -40,6 +42,9 @@ func (m *Mapping) GetServer() error {
 			}
 			deployPath := strings.TrimSuffix(s.DeployPath, "/bin")
 			c.DeployPath = deployPath
+			if s.StatusPort != 0 {
+				c.StatusPort = strconv.Itoa(s.StatusPort)
+			}
 			cs = append(cs, c)
 		}
 	}
@@ -60,3 +65,21 @@ func GetTiDBHostStatusPort() (string, string, error) {
 	statusPort := strconv.FormatInt(rs.Values[0][3].AsInt64(), 10)
 	return host, statusPort, nil
 }
+
+type TiDBStatus struct {
+	Connections int    `json:"connections"`
+	Version     string `json:"version"`
+}
+
+// GetTiDBStatus requests the status port of tidb, an error means the tidb is not serving.
+func GetTiDBStatus(statusAddr string) (*TiDBStatus, error) {
+	resp, err := http.Get(fmt.Sprintf(tidbStatusUrl, statusAddr))
+	if err != nil {
+		return nil, err
+	}
+	var s *TiDBStatus
+	if err := json.Unmarshal(resp, &s); err != nil {
+		return nil, fmt.Errorf("tidb %s is not serving: %w", statusAddr, err)
+	}
+	return s, nil
+}
@@ -7,6 +7,7 @@ import (
 	"pictorial/ssh"
 	"pictorial/util/http"
 	"strings"
+	"time"
 )
 
 type Store struct {
@@ -19,10 +20,75 @@ type Store struct {
 				Key   string `json:"key"`
 				Value string `json:"value"`
 			} `json:"labels"`
+			StateName string `json:"state_name"`
 		} `json:"store"`
+		Status struct {
+			RegionCount     int       `json:"region_count"`
+			LeaderCount     int       `json:"leader_count"`
+			LastHeartbeatTS time.Time `json:"last_heartbeat_ts"`
+		} `json:"status"`
 	} `json:"stores"`
 }
 
+type StoreStatus struct {
+	Address         string
+	StateName       string
+	RegionCount     int
+	LeaderCount     int
+	LastHeartbeatTS time.Time
+	Labels          map[string]string
+}
+
+const StoreUp = "Up"
+
+// IsTiFlash reports whether the store is a tiflash, which is labeled by engine=tiflash.
+func (s *StoreStatus) IsTiFlash() bool {
+	for _, v := range s.Labels {
+		if v == "tiflash" {
+			return true
+		}
+	}
+	return false
+}
+
+func GetStoreStatus(addr string) (*StoreStatus, error) {
+	ss, err := GetStoreStatuses()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := ss[addr]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("store %s is not found", addr)
+}
+
+// GetStoreStatuses returns the status of all stores by address.
+func GetStoreStatuses() (map[string]*StoreStatus, error) {
+	resp, err := http.Get(fmt.Sprintf(storeUrl, PdAddr))
+	if err != nil {
+		return nil, err
+	}
+	var store *Store
+	if err := json.Unmarshal(resp, &store); err != nil {
+		return nil, fmt.Errorf("pd %s is not serving: %w", PdAddr, err)
+	}
+	ss := make(map[string]*StoreStatus)
+	for _, s := range store.Stores {
+		ss[s.Store.Address] = &StoreStatus{
+			Address:         s.Store.Address,
+			StateName:       s.Store.StateName,
+			RegionCount:     s.Status.RegionCount,
+			LeaderCount:     s.Status.LeaderCount,
+			LastHeartbeatTS: s.Status.LastHeartbeatTS,
+			Labels:          map[string]string{},
+		}
+		for _, l := range s.Store.Labels {
+			ss[s.Store.Address].Labels[l.Key] = l.Value
+		}
+	}
+	return ss, nil
+}
+
 func (m *Mapping) GetStore() error {
 	resp, err := http.Get(fmt.Sprintf(storeUrl, PdAddr))
 	if err != nil {
@@ -0,0 +1,202 @@
+package comp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"pictorial/mysql"
+	"pictorial/util/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Symbolic targets are resolved to an address when the job runs, the roles may move after the tree is built.
+const (
+	// PDLeader is the current leader of pd.
+	PDLeader = "pd-leader"
+	// RegionLeader is the tikv holding the leader of the first region of a table, e.g. region-leader:poc.t1.
+	RegionLeader = "region-leader"
+	// DDLOwner is the tidb which owns ddl.
+	DDLOwner = "ddl-owner"
+	// RandomTiKV is a random tikv which is up, e.g. random-tikv or random-tikv:zone=z1.
+	RandomTiKV = "random-tikv"
+)
+
+const symbolSeparator = ":"
+
+const regionLeaderSQL = "SELECT tss.address FROM information_schema.tikv_region_peers trp " +
+	"JOIN information_schema.tikv_store_status tss ON tss.store_id = trp.store_id " +
+	"JOIN information_schema.tikv_region_status trs ON trp.region_id = trs.region_id " +
+	"WHERE trs.db_name = '%s' AND trs.table_name = '%s' AND trp.is_leader = 1 ORDER BY trs.region_id LIMIT 1"
+
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+var symbolCType = map[string]CType{
+	PDLeader:     PD,
+	RegionLeader: TiKV,
+	DDLOwner:     TiDB,
+	RandomTiKV:   TiKV,
+}
+
+func splitSymbol(v string) (string, string) {
+	name, arg, _ := strings.Cut(v, symbolSeparator)
+	return name, arg
+}
+
+// IsSymbol reports whether v is a symbolic target rather than an address.
+func IsSymbol(v string) bool {
+	name, _ := splitSymbol(v)
+	_, ok := symbolCType[name]
+	return ok
+}
+
+// SymbolCType returns the component type which the symbol resolves to.
+func SymbolCType(v string) (CType, bool) {
+	name, _ := splitSymbol(v)
+	c, ok := symbolCType[name]
+	return c, ok
+}
+
+// Symbols lists the symbolic targets of the component type shown in the tree, region-leader needs a table so it is not listed.
+func Symbols(cType CType, cs []Component) []string {
+	switch cType {
+	case PD:
+		return []string{PDLeader}
+	case TiDB:
+		return []string{DDLOwner}
+	case TiKV:
+		symbols := []string{RandomTiKV}
+		var labels []string
+		visited := make(map[string]bool)
+		for _, c := range cs {
+			for k, v := range c.Labels {
+				l := fmt.Sprintf("%s=%s", k, v)
+				if !visited[l] {
+					visited[l] = true
+					labels = append(labels, l)
+				}
+			}
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			symbols = append(symbols, RandomTiKV+symbolSeparator+l)
+		}
+		return symbols
+	}
+	return nil
+}
+
+// Resolve returns the address which the symbolic target points to now.
+func Resolve(v string) (string, error) {
+	name, arg := splitSymbol(v)
+	var addr string
+	var err error
+	switch name {
+	case PDLeader:
+		addr, err = getPDLeader()
+	case RegionLeader:
+		addr, err = getRegionLeader(arg)
+	case DDLOwner:
+		addr, err = getDDLOwner()
+	case RandomTiKV:
+		addr, err = getRandomTiKV(arg)
+	default:
+		return "", fmt.Errorf("unknown target: %s", v)
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve %s failed: %w", v, err)
+	}
+	return addr, nil
+}
+
+func getPDLeader() (string, error) {
+	resp, err := http.Get(fmt.Sprintf(membersUrl, PdAddr))
+	if err != nil {
+		return "", err
+	}
+	var pd *PlacementDriver
+	if err := json.Unmarshal(resp, &pd); err != nil {
+		return "", fmt.Errorf("pd %s is not serving: %w", PdAddr, err)
+	}
+	if len(pd.Leader.ClientURLs) == 0 {
+		return "", fmt.Errorf("pd has no leader")
+	}
+	return http.ClearHttpHeader(pd.Leader.ClientURLs[0]), nil
+}
+
+// getRegionLeader accepts db.table or table, the database is test if omitted.
+func getRegionLeader(table string) (string, error) {
+	if table == "" {
+		return "", fmt.Errorf("table is required, e.g. %s:poc.t1", RegionLeader)
+	}
+	db, t, ok := strings.Cut(table, ".")
+	if !ok {
+		db, t = "test", table
+	}
+	rs, err := mysql.M.ExecuteSQL(fmt.Sprintf(regionLeaderSQL, mysql.Escape(db), mysql.Escape(t)))
+	if err != nil {
+		return "", err
+	}
+	defer rs.Close()
+	if len(rs.Values) == 0 {
+		return "", fmt.Errorf("no region leader of %s.%s", db, t)
+	}
+	return string(rs.Values[0][0].AsString()), nil
+}
+
+type tidbAllInfo struct {
+	OwnerID        string `json:"owner_id"`
+	AllServersInfo map[string]struct {
+		DDLID         string `json:"ddl_id"`
+		IP            string `json:"ip"`
+		ListeningPort int    `json:"listening_port"`
+	} `json:"all_servers_info"`
+}
+
+func getDDLOwner() (string, error) {
+	host, statusPort, err := GetTiDBHostStatusPort()
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.Get(fmt.Sprintf(tidbAllInfoUrl, net.JoinHostPort(host, statusPort)))
+	if err != nil {
+		return "", err
+	}
+	var info *tidbAllInfo
+	if err := json.Unmarshal(resp, &info); err != nil {
+		return "", fmt.Errorf("tidb %s is not serving: %w", host, err)
+	}
+	for _, s := range info.AllServersInfo {
+		if s.DDLID == info.OwnerID {
+			return net.JoinHostPort(s.IP, strconv.Itoa(s.ListeningPort)), nil
+		}
+	}
+	return "", fmt.Errorf("ddl owner %s is not found", info.OwnerID)
+}
+
+// getRandomTiKV picks a tikv which is up, label is key=value or empty.
+func getRandomTiKV(label string) (string, error) {
+	key, value, _ := strings.Cut(label, "=")
+	ss, err := GetStoreStatuses()
+	if err != nil {
+		return "", err
+	}
+	var candidates []string
+	for addr, s := range ss {
+		if s.StateName != StoreUp || s.IsTiFlash() {
+			continue
+		}
+		if key != "" && s.Labels[key] != value {
+			continue
+		}
+		candidates = append(candidates, addr)
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no tikv is up with label %s", label)
+	}
+	sort.Strings(candidates)
+	return candidates[random.Intn(len(candidates))], nil
+}
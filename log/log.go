@@ -3,6 +3,7 @@ package log
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -49,6 +50,16 @@ func Track(logName string) (*tail.Tail, error) {
 	})
 }
 
+// TrackFrom tracks the log from the offset, the lines written before it are skipped.
+func TrackFrom(logName string, offset int64) (*tail.Tail, error) {
+	return tail.TailFile(logName, tail.Config{
+		ReOpen:   true,
+		Follow:   true,
+		Poll:     false,
+		Location: &tail.SeekInfo{Offset: offset, Whence: io.SeekStart},
+	})
+}
+
 func DateFormat() string {
 	now := time.Now()
 	year, month, day := now.Date()
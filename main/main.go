@@ -1,7 +1,18 @@
 package main
 
-import "pictorial/server"
+import (
+	"os"
+	"pictorial/server"
+)
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case server.RunCommand:
+			os.Exit(server.Run(os.Args[2:]))
+		case server.ScenarioCommand:
+			os.Exit(server.Scenario(os.Args[2:]))
+		}
+	}
 	server.New()
 }
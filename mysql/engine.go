@@ -0,0 +1,324 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"io"
+	"net"
+	"os"
+	"pictorial/log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StatementResult struct {
+	SQL          string
+	Line         int
+	Columns      []string
+	Numeric      []bool
+	Rows         [][]string
+	IsResultSet  bool
+	AffectedRows uint64
+	Warnings     []Warning
+	ErrCode      uint16
+	ErrState     string
+	ErrMsg       string
+	Duration     time.Duration
+}
+
+type Warning struct {
+	Level   string
+	Code    uint16
+	Message string
+}
+
+func (r *StatementResult) Failed() bool {
+	return r.ErrCode != 0
+}
+
+// ConnectionLost reports whether the statement failed for the connection rather than the server.
+func (r *StatementResult) ConnectionLost() bool {
+	return r.ErrCode == crConnectionError || r.ErrCode == crServerLost
+}
+
+// Session is one connection of a script, like a mysql client started with --force.
+type Session struct {
+	m        *MySQL
+	user     string
+	password string
+	conn     *client.Conn
+	// timeout of the connecting and each statement, no timeout if 0.
+	timeout time.Duration
+}
+
+const (
+	crConnectionError = 2003
+	crServerLost      = 2013
+	unknownState      = "HY000"
+)
+
+func (m *MySQL) NewSession(user, password string) (*Session, error) {
+	return m.NewSessionWithTimeout(user, password, 0)
+}
+
+// NewSessionWithTimeout returns the session even if the connecting failed, it reconnects on the next statement.
+func (m *MySQL) NewSessionWithTimeout(user, password string, timeout time.Duration) (*Session, error) {
+	s := &Session{
+		m:        m,
+		user:     user,
+		password: password,
+		timeout:  timeout,
+	}
+	return s, s.connect()
+}
+
+func (s *Session) connect() error {
+	addr := net.JoinHostPort(s.m.Host, s.m.Port)
+	conn, err := client.Connect(addr, s.user, s.password, "", func(c *client.Conn) {
+		c.SetCapability(mysql.CLIENT_LOCAL_FILES)
+		s.deadline(c)
+	})
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Session) deadline(c *client.Conn) {
+	if s.timeout > 0 {
+		_ = c.SetDeadline(time.Now().Add(s.timeout))
+	}
+}
+
+func (s *Session) Close() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// Execute runs one statement, the error of the statement is kept in the result.
+func (s *Session) Execute(sql string) *StatementResult {
+	r := &StatementResult{
+		SQL: sql,
+	}
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			r.setErr(err, crConnectionError)
+			return r
+		}
+	}
+	log.Logger.Debug(sql)
+	s.deadline(s.conn)
+	start := time.Now()
+	var rs *mysql.Result
+	var err error
+	if isLoadDataLocal(sql) {
+		rs, err = s.loadDataLocal(sql)
+	} else {
+		rs, err = s.conn.Execute(sql)
+	}
+	r.Duration = time.Since(start)
+	if err != nil {
+		r.setErr(err, crServerLost)
+		if _, ok := cause(err).(*mysql.MyError); !ok {
+			s.Close()
+			s.conn = nil
+		}
+		return r
+	}
+	defer rs.Close()
+	r.AffectedRows = rs.AffectedRows
+	if rs.Resultset != nil && len(rs.Fields) != 0 {
+		r.IsResultSet = true
+		for _, f := range rs.Fields {
+			r.Columns = append(r.Columns, string(f.Name))
+			r.Numeric = append(r.Numeric, isNumeric(f.Type))
+		}
+		for _, row := range rs.Values {
+			var values []string
+			for _, v := range row {
+				values = append(values, fieldString(v))
+			}
+			r.Rows = append(r.Rows, values)
+		}
+	}
+	if rs.Warnings > 0 {
+		r.Warnings = s.showWarnings()
+	}
+	return r
+}
+
+// cause unwraps the errors.Trace of go-mysql.
+func cause(err error) error {
+	for {
+		c, ok := err.(interface{ Cause() error })
+		if !ok || c.Cause() == nil || c.Cause() == err {
+			return err
+		}
+		err = c.Cause()
+	}
+}
+
+func (r *StatementResult) setErr(err error, code uint16) {
+	if myErr, ok := cause(err).(*mysql.MyError); ok {
+		r.ErrCode = myErr.Code
+		r.ErrState = myErr.State
+		r.ErrMsg = myErr.Message
+		return
+	}
+	r.ErrCode = code
+	r.ErrState = unknownState
+	r.ErrMsg = err.Error()
+}
+
+func (s *Session) showWarnings() []Warning {
+	rs, err := s.conn.Execute("SHOW WARNINGS")
+	if err != nil {
+		return nil
+	}
+	defer rs.Close()
+	var ws []Warning
+	for _, row := range rs.Values {
+		if len(row) < 3 {
+			continue
+		}
+		ws = append(ws, Warning{
+			Level:   string(row[0].AsString()),
+			Code:    uint16(row[1].AsInt64()),
+			Message: string(row[2].AsString()),
+		})
+	}
+	return ws
+}
+
+var loadDataLocalReg = regexp.MustCompile(`(?is)^\s*LOAD\s+DATA\s+(LOW_PRIORITY\s+|CONCURRENT\s+)?LOCAL\s+INFILE`)
+
+func isLoadDataLocal(sql string) bool {
+	return loadDataLocalReg.MatchString(sql)
+}
+
+const localInFileChunk = 64 * 1024
+
+// loadDataLocal serves the LOCAL INFILE request of the server, go-mysql does not.
+func (s *Session) loadDataLocal(sql string) (*mysql.Result, error) {
+	c := s.conn
+	c.ResetSequence()
+	query := make([]byte, 5, 5+len(sql))
+	query[4] = mysql.COM_QUERY
+	query = append(query, sql...)
+	if err := c.WritePacket(query); err != nil {
+		return nil, err
+	}
+	data, err := c.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	switch data[0] {
+	case mysql.OK_HEADER:
+		return c.HandleOKPacket(data), nil
+	case mysql.ERR_HEADER:
+		return nil, c.HandleErrorPacket(data)
+	case mysql.LocalInFile_HEADER:
+	default:
+		return nil, mysql.ErrMalformPacket
+	}
+	sendErr := sendLocalFile(c, string(data[1:]))
+	if err := c.WritePacket(make([]byte, 4)); err != nil {
+		return nil, err
+	}
+	rs, err := c.ReadOKPacket()
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	return rs, err
+}
+
+func sendLocalFile(c *client.Conn, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	buf := make([]byte, 4+localInFileChunk)
+	for {
+		n, err := f.Read(buf[4:])
+		if n > 0 {
+			if err := c.WritePacket(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func fieldString(v mysql.FieldValue) string {
+	switch v.Type {
+	case mysql.FieldValueTypeNull:
+		return "NULL"
+	case mysql.FieldValueTypeUnsigned:
+		return strconv.FormatUint(v.AsUint64(), 10)
+	case mysql.FieldValueTypeSigned:
+		return strconv.FormatInt(v.AsInt64(), 10)
+	case mysql.FieldValueTypeFloat:
+		return strconv.FormatFloat(v.AsFloat64(), 'f', -1, 64)
+	default:
+		return string(v.AsString())
+	}
+}
+
+func isNumeric(tp byte) bool {
+	switch tp {
+	case mysql.MYSQL_TYPE_TINY, mysql.MYSQL_TYPE_SHORT, mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_INT24,
+		mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_FLOAT, mysql.MYSQL_TYPE_DOUBLE,
+		mysql.MYSQL_TYPE_DECIMAL, mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_YEAR:
+		return true
+	}
+	return false
+}
+
+// ExecuteScript splits the script and runs the statements one by one, it goes on after a failed statement.
+func (m *MySQL) ExecuteScript(script, user, password string) ([]*StatementResult, error) {
+	s, err := m.NewSession(user, password)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	var rs []*StatementResult
+	for _, stmt := range SplitStatements(script) {
+		r := s.Execute(stmt.SQL)
+		r.Line = stmt.Line
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+func (m *MySQL) ExecuteForceWithOutput(sql, user, password string) ([]string, error) {
+	rs, err := m.ExecuteScript(sql, user, password)
+	if err != nil {
+		r := &StatementResult{}
+		r.setErr(err, crConnectionError)
+		e := errorLine(r, false)
+		return []string{"", e, ""}, errors.New(e)
+	}
+	var errs []string
+	for _, r := range rs {
+		if r.Failed() {
+			errs = append(errs, errorLine(r, true))
+		}
+	}
+	output := Render(rs)
+	if len(errs) != 0 {
+		return output, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return output, nil
+}
@@ -0,0 +1,328 @@
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// directives of the .result file, they take effect on the next statement.
+const (
+	directive     = "--"
+	sortedResult  = "--sorted_result"
+	replaceColumn = "--replace_column"
+	replaceRegex  = "--replace_regex"
+	expectError   = "--error"
+)
+
+var (
+	timingReg = regexp.MustCompile(`\(\d+(\.\d+)? (sec|min)\)`)
+	errorReg  = regexp.MustCompile(`^ERROR (\d+)`)
+	borderReg = regexp.MustCompile(`-+`)
+)
+
+type statement struct {
+	lines         []string
+	sorted        bool
+	replaceColumn map[int]string
+	replaceRegex  []replacement
+	errors        []int
+}
+
+type replacement struct {
+	reg *regexp.Regexp
+	to  string
+}
+
+// Compare diffs the transcript against the expected .result content, an empty result means they match.
+func Compare(expected []byte, actual []string) ([]string, error) {
+	exp, err := parseStatements(bufio.NewScanner(bytes.NewReader(expected)), true)
+	if err != nil {
+		return nil, err
+	}
+	act, err := parseStatements(bufio.NewScanner(strings.NewReader(strings.Join(actual, "\n"))), false)
+	if err != nil {
+		return nil, err
+	}
+	var diff []string
+	for i := 0; i < len(exp) || i < len(act); i++ {
+		switch {
+		case i >= len(act):
+			diff = append(diff, fmt.Sprintf("@@ statement %d: missing", i+1))
+			diff = append(diff, prefix("- ", exp[i].lines)...)
+			continue
+		case i >= len(exp):
+			diff = append(diff, fmt.Sprintf("@@ statement %d: unexpected", i+1))
+			diff = append(diff, prefix("+ ", act[i].lines)...)
+			continue
+		}
+		e, a := exp[i], act[i]
+		if len(e.errors) != 0 {
+			if msg := e.checkError(a.lines); msg != "" {
+				diff = append(diff, fmt.Sprintf("@@ statement %d: %s", i+1, head(e.lines)))
+				diff = append(diff, msg)
+			}
+			e.lines, a.lines = dropError(e.lines), dropError(a.lines)
+		}
+		el, al := e.normalize(e.lines), e.normalize(a.lines)
+		if d := lineDiff(el, al); len(d) != 0 {
+			diff = append(diff, fmt.Sprintf("@@ statement %d: %s", i+1, head(e.lines)))
+			diff = append(diff, d...)
+		}
+	}
+	return diff, nil
+}
+
+func parseStatements(sc *bufio.Scanner, withDirective bool) ([]*statement, error) {
+	var stmts []*statement
+	pending := &statement{}
+	var current *statement
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		switch {
+		case withDirective && strings.HasPrefix(line, directive) && !strings.HasPrefix(line, mysqlCli):
+			if err := pending.addDirective(line); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, mysqlCli):
+			current = pending
+			current.lines = []string{line}
+			stmts = append(stmts, current)
+			pending = &statement{}
+		case current != nil && line != "":
+			current.lines = append(current.lines, line)
+		}
+	}
+	return stmts, sc.Err()
+}
+
+func (s *statement) addDirective(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case sortedResult:
+		s.sorted = true
+	case replaceColumn:
+		if len(fields) < 3 || len(fields)%2 == 0 {
+			return fmt.Errorf("invalid directive: %s, e.g. %s 1 #", line, replaceColumn)
+		}
+		if s.replaceColumn == nil {
+			s.replaceColumn = make(map[int]string)
+		}
+		for i := 1; i < len(fields); i += 2 {
+			col, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return fmt.Errorf("invalid directive: %s: %w", line, err)
+			}
+			s.replaceColumn[col] = fields[i+1]
+		}
+	case replaceRegex:
+		rs, err := parseReplaceRegex(strings.TrimSpace(strings.TrimPrefix(line, replaceRegex)))
+		if err != nil {
+			return fmt.Errorf("invalid directive: %s, e.g. %s /regex/replacement/: %w", line, replaceRegex, err)
+		}
+		s.replaceRegex = append(s.replaceRegex, rs...)
+	case expectError:
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid directive: %s, e.g. %s 1062", line, expectError)
+		}
+		for _, c := range strings.Split(fields[1], ",") {
+			code, err := strconv.Atoi(c)
+			if err != nil {
+				return fmt.Errorf("invalid directive: %s: %w", line, err)
+			}
+			s.errors = append(s.errors, code)
+		}
+	default:
+		return fmt.Errorf("unknown directive: %s", line)
+	}
+	return nil
+}
+
+// parseReplaceRegex parses /regex/replacement/ pairs separated by spaces, the first char is the delimiter
+// like mysqltest, so /a\/b/c/ or |a/b|c| matches a/b.
+func parseReplaceRegex(v string) ([]replacement, error) {
+	var rs []replacement
+	for v != "" {
+		delim := v[0]
+		var parts []string
+		var sb strings.Builder
+		i := 1
+		for ; i < len(v) && len(parts) < 2; i++ {
+			switch {
+			case v[i] == '\\' && i+1 < len(v) && v[i+1] == delim:
+				sb.WriteByte(delim)
+				i++
+			case v[i] == delim:
+				parts = append(parts, sb.String())
+				sb.Reset()
+			default:
+				sb.WriteByte(v[i])
+			}
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unterminated %q", v)
+		}
+		reg, err := regexp.Compile(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, replacement{reg: reg, to: parts[1]})
+		v = strings.TrimSpace(v[i:])
+	}
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no regex")
+	}
+	return rs, nil
+}
+
+func (s *statement) checkError(lines []string) string {
+	for _, l := range lines {
+		m := errorReg.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		code, _ := strconv.Atoi(m[1])
+		for _, e := range s.errors {
+			if e == code {
+				return ""
+			}
+		}
+		return fmt.Sprintf("! expected error %v, got: %s", s.errors, l)
+	}
+	return fmt.Sprintf("! expected error %v, but succeeded", s.errors)
+}
+
+func dropError(lines []string) []string {
+	var r []string
+	for _, l := range lines {
+		if !errorReg.MatchString(l) {
+			r = append(r, l)
+		}
+	}
+	return r
+}
+
+// normalize masks the timing, rebuilds the table without alignment and applies the directives.
+func (s *statement) normalize(lines []string) []string {
+	var r []string
+	var rows []string
+	border := 0
+	flush := func() {
+		if s.sorted {
+			sort.Strings(rows)
+		}
+		r = append(r, rows...)
+		rows = nil
+	}
+	for _, l := range lines {
+		l = timingReg.ReplaceAllString(l, "")
+		for _, rp := range s.replaceRegex {
+			l = rp.reg.ReplaceAllString(l, rp.to)
+		}
+		switch {
+		case strings.HasPrefix(l, "+") && strings.HasSuffix(l, "+"):
+			border++
+			flush()
+			r = append(r, borderReg.ReplaceAllString(l, "-"))
+		case strings.HasPrefix(l, "|") && border >= 2:
+			rows = append(rows, s.row(l))
+		case strings.HasPrefix(l, "|"):
+			r = append(r, s.cells(l))
+		default:
+			flush()
+			border = 0
+			r = append(r, strings.TrimSpace(l))
+		}
+	}
+	flush()
+	return r
+}
+
+func (s *statement) row(l string) string {
+	cells := splitCells(l)
+	for col, v := range s.replaceColumn {
+		if col >= 1 && col <= len(cells) {
+			cells[col-1] = v
+		}
+	}
+	return joinCells(cells)
+}
+
+func (s *statement) cells(l string) string {
+	return joinCells(splitCells(l))
+}
+
+func splitCells(l string) []string {
+	l = strings.TrimPrefix(strings.TrimSuffix(l, "|"), "|")
+	cells := strings.Split(l, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func joinCells(cells []string) string {
+	return fmt.Sprintf("| %s |", strings.Join(cells, " | "))
+}
+
+// lineDiff is a plain lcs diff, statements are small.
+func lineDiff(e, a []string) []string {
+	n, m := len(e), len(a)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if e[i] == a[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff []string
+	changed := false
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && e[i] == a[j]:
+			diff = append(diff, "  "+e[i])
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+e[i])
+			changed = true
+			i++
+		default:
+			diff = append(diff, "+ "+a[j])
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return diff
+}
+
+func prefix(p string, lines []string) []string {
+	var r []string
+	for _, l := range lines {
+		r = append(r, p+l)
+	}
+	return r
+}
+
+func head(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(lines[0], mysqlCli+" ")
+}
@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const mysqlCli = "mysql>"
+const mysqlCliWarp = "    ->"
+const selectSleep = "SELECT SLEEP"
+
+// Render prints the results in the transcript format of "mysql -vvv --force".
+func Render(rs []*StatementResult) []string {
+	result := []string{""}
+	for _, r := range rs {
+		if strings.HasPrefix(r.SQL, selectSleep) {
+			continue
+		}
+		result = append(result, echo(r.SQL)...)
+		switch {
+		case r.Failed():
+			result = append(result, errorLine(r, true))
+		case r.IsResultSet:
+			result = append(result, table(r)...)
+			if len(r.Rows) == 0 {
+				result = append(result, fmt.Sprintf("Empty set%s (%s)", warningCount(r), seconds(r)))
+			} else {
+				result = append(result, fmt.Sprintf("%d %s in set%s (%s)", len(r.Rows), plural(len(r.Rows), "row"), warningCount(r), seconds(r)))
+			}
+		default:
+			result = append(result, fmt.Sprintf("Query OK, %d %s affected%s (%s)", r.AffectedRows, plural(int(r.AffectedRows), "row"), warningCount(r), seconds(r)))
+		}
+		for _, w := range r.Warnings {
+			result = append(result, fmt.Sprintf("%s (Code %d): %s", w.Level, w.Code, w.Message))
+		}
+		result = append(result, "")
+	}
+	return result
+}
+
+func echo(sql string) []string {
+	lines := strings.Split(sql, "\n")
+	var r []string
+	for i, l := range lines {
+		if i == 0 {
+			l = fmt.Sprintf("%s %s", mysqlCli, l)
+		} else {
+			l = fmt.Sprintf("%s %s", mysqlCliWarp, l)
+		}
+		if i == len(lines)-1 {
+			l += defaultDelimiter
+		}
+		r = append(r, l)
+	}
+	return r
+}
+
+func errorLine(r *StatementResult, withLine bool) string {
+	if withLine {
+		return fmt.Sprintf("ERROR %d (%s) at line %d: %s", r.ErrCode, r.ErrState, r.Line, r.ErrMsg)
+	}
+	return fmt.Sprintf("ERROR %d (%s): %s", r.ErrCode, r.ErrState, r.ErrMsg)
+}
+
+// table is nothing for an empty set, like the mysql client.
+func table(r *StatementResult) []string {
+	if len(r.Rows) == 0 {
+		return nil
+	}
+	width := make([]int, len(r.Columns))
+	for i, c := range r.Columns {
+		width[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range r.Rows {
+		for i, v := range row {
+			if i < len(width) && utf8.RuneCountInString(v) > width[i] {
+				width[i] = utf8.RuneCountInString(v)
+			}
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("+")
+	for _, w := range width {
+		sb.WriteString(strings.Repeat("-", w+2))
+		sb.WriteString("+")
+	}
+	border := sb.String()
+	line := func(values []string, header bool) string {
+		var sb strings.Builder
+		sb.WriteString("|")
+		for i, v := range values {
+			if i >= len(width) {
+				break
+			}
+			pad := strings.Repeat(" ", width[i]-utf8.RuneCountInString(v))
+			if !header && r.Numeric[i] {
+				sb.WriteString(fmt.Sprintf(" %s%s |", pad, v))
+			} else {
+				sb.WriteString(fmt.Sprintf(" %s%s |", v, pad))
+			}
+		}
+		return sb.String()
+	}
+	result := []string{border, line(r.Columns, true), border}
+	for _, row := range r.Rows {
+		result = append(result, line(row, false))
+	}
+	return append(result, border)
+}
+
+func warningCount(r *StatementResult) string {
+	if len(r.Warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d %s", len(r.Warnings), plural(len(r.Warnings), "warning"))
+}
+
+func plural(n int, v string) string {
+	if n == 1 {
+		return v
+	}
+	return v + "s"
+}
+
+func seconds(r *StatementResult) string {
+	return fmt.Sprintf("%.2f sec", r.Duration.Seconds())
+}
@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"strings"
+)
+
+type Statement struct {
+	SQL  string
+	Line int
+}
+
+const defaultDelimiter = ";"
+const delimiterCmd = "delimiter"
+
+// SplitStatements splits a script by the delimiter, quotes and comments are respected
+// and the "DELIMITER" command of the mysql client is supported.
+func SplitStatements(script string) []Statement {
+	var stmts []Statement
+	delimiter := defaultDelimiter
+	var sb strings.Builder
+	blank := true
+	line, start := 1, 1
+	write := func(v string) {
+		sb.WriteString(v)
+		if blank && strings.TrimSpace(v) != "" {
+			blank = false
+		}
+	}
+	flush := func() {
+		sql := strings.TrimSpace(sb.String())
+		sb.Reset()
+		blank = true
+		if sql != "" && !isComment(sql) {
+			stmts = append(stmts, Statement{SQL: sql, Line: start})
+		}
+	}
+	for i := 0; i < len(script); {
+		c := script[i]
+		if blank {
+			start = line
+			if d, n, ok := parseDelimiter(script[i:]); ok {
+				delimiter = d
+				line += strings.Count(script[i:i+n], "\n")
+				i += n
+				sb.Reset()
+				continue
+			}
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			n := skipQuote(script[i:], c)
+			line += strings.Count(script[i:i+n], "\n")
+			write(script[i : i+n])
+			i += n
+		case strings.HasPrefix(script[i:], "/*"):
+			n := strings.Index(script[i+2:], "*/")
+			if n == -1 {
+				n = len(script) - i
+			} else {
+				n += 4
+			}
+			line += strings.Count(script[i:i+n], "\n")
+			write(script[i : i+n])
+			i += n
+		case c == '#' || isDashComment(script[i:]):
+			n := strings.Index(script[i:], "\n")
+			if n == -1 {
+				n = len(script) - i
+			}
+			write(script[i : i+n])
+			i += n
+		case strings.HasPrefix(script[i:], delimiter):
+			flush()
+			i += len(delimiter)
+		default:
+			if c == '\n' {
+				line++
+			}
+			write(script[i : i+1])
+			i++
+		}
+	}
+	flush()
+	return stmts
+}
+
+func skipQuote(s string, q byte) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if q != '`' {
+				i++
+			}
+		case q:
+			if i+1 < len(s) && s[i+1] == q {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isDashComment(s string) bool {
+	return strings.HasPrefix(s, "--") && (len(s) == 2 || s[2] == ' ' || s[2] == '\t' || s[2] == '\n' || s[2] == '\r')
+}
+
+// isComment reports whether the statement only has comments.
+func isComment(sql string) bool {
+	for _, l := range strings.Split(sql, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "#") && !isDashComment(l) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDelimiter(s string) (string, int, bool) {
+	trimmed := strings.TrimLeft(s, " \t\r\n")
+	skipped := len(s) - len(trimmed)
+	if len(trimmed) <= len(delimiterCmd) || !strings.EqualFold(trimmed[:len(delimiterCmd)], delimiterCmd) {
+		return "", 0, false
+	}
+	if c := trimmed[len(delimiterCmd)]; c != ' ' && c != '\t' {
+		return "", 0, false
+	}
+	end := strings.Index(trimmed, "\n")
+	if end == -1 {
+		end = len(trimmed)
+	}
+	d := strings.TrimSpace(trimmed[len(delimiterCmd):end])
+	if d == "" {
+		return "", 0, false
+	}
+	return d, skipped + end, true
+}
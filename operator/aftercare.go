@@ -0,0 +1,34 @@
+package operator
+
+import (
+	"sync"
+	"time"
+)
+
+// aftercare tracks the goroutines which undo the faults when the job is cancelled, e.g. the iptables rules and the
+// fill file of disk_full, the ssh connections are kept until they are done.
+var aftercare sync.WaitGroup
+
+// goAftercare runs fn in a goroutine tracked by WaitAftercare.
+func goAftercare(fn func()) {
+	aftercare.Add(1)
+	go func() {
+		defer aftercare.Done()
+		fn()
+	}()
+}
+
+// WaitAftercare waits for the aftercare after the job is cancelled, false if it is not done in the timeout.
+func WaitAftercare(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		aftercare.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -14,6 +14,8 @@ type Builder struct {
 	comp.CType
 	DeployPath string
 	Ctx        context.Context
+	Peers      []string
+	Topology   string
 }
 
 type OType int
@@ -42,6 +44,11 @@ const (
 	LoadData
 	LoadDataSelectIntoOutFile
 	InstallSysBench
+	NetworkIsolation
+	NetworkDelay
+	NetworkPartition
+	ScaleOut
+	HtapWorkload
 )
 
 func GetOTypeValue(o OType) string {
@@ -92,15 +99,40 @@ func GetOTypeValue(o OType) string {
 		return "add_index_performance"
 	case InstallSysBench:
 		return "install_sysbench"
+	case NetworkIsolation:
+		return "network_isolation"
+	case NetworkDelay:
+		return "network_delay"
+	case NetworkPartition:
+		return "network_partition"
+	case ScaleOut:
+		return "scale_out"
+	case HtapWorkload:
+		return "htap_workload"
 	default:
 		return ""
 	}
 }
 
+// GetOTypeByValue is the reverse of GetOTypeValue.
+func GetOTypeByValue(v string) (OType, bool) {
+	for o := Script; GetOTypeValue(o) != ""; o++ {
+		if GetOTypeValue(o) == v {
+			return o, true
+		}
+	}
+	return 0, false
+}
+
 type Operator interface {
 	Execute() error
 }
 
+// Recoverable is an Operator which leaves something to restore after the fault, e.g. the systemd or the data dir.
+type Recoverable interface {
+	Recover() error
+}
+
 func (b *Builder) Build() (Operator, error) {
 	switch b.OType {
 	case ScaleIn:
@@ -117,8 +149,14 @@ func (b *Builder) Build() (Operator, error) {
 		return b.BuildReboot()
 	case DiskFull:
 		return b.BuildDiskFull()
+	case NetworkIsolation, NetworkPartition:
+		return b.BuildNetworkIsolation()
+	case NetworkDelay:
+		return b.BuildNetworkDelay()
+	case ScaleOut:
+		return b.BuildScaleOut()
 	default:
-		return nil, fmt.Errorf("unknown operator: %s", b.OType)
+		return nil, fmt.Errorf("unknown operator: %d", b.OType)
 	}
 }
 
@@ -182,3 +220,33 @@ func (b *Builder) BuildDiskFull() (Operator, error) {
 		ctx:        b.Ctx,
 	}, nil
 }
+
+func (b *Builder) BuildNetworkIsolation() (Operator, error) {
+	return &networkIsolationOperator{
+		oType: b.OType,
+		host:  b.Host,
+		port:  b.Port,
+		cType: b.CType,
+		peers: b.Peers,
+		ctx:   b.Ctx,
+	}, nil
+}
+
+func (b *Builder) BuildNetworkDelay() (Operator, error) {
+	return &networkDelayOperator{
+		host:  b.Host,
+		port:  b.Port,
+		cType: b.CType,
+		peers: b.Peers,
+		ctx:   b.Ctx,
+	}, nil
+}
+
+func (b *Builder) BuildScaleOut() (Operator, error) {
+	return &scaleOutOperator{
+		host:     b.Host,
+		cType:    b.CType,
+		topology: b.Topology,
+		ctx:      b.Ctx,
+	}, nil
+}
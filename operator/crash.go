@@ -14,6 +14,7 @@ type crashOperator struct {
 	port       string
 	cType      comp.CType
 	deployPath string
+	service    string
 }
 
 const systemdPath = "/etc/systemd/system/"
@@ -32,8 +33,8 @@ func (c *crashOperator) Execute() error {
 		c.port = port
 	}
 	systemd := fmt.Sprintf(serviceFile, cType, c.port)
-	service := filepath.Join(systemdPath, systemd)
-	if _, err := ssh.S.Systemd(c.host, ssh.No, service); err != nil {
+	c.service = filepath.Join(systemdPath, systemd)
+	if _, err := ssh.S.Systemd(c.host, ssh.No, c.service); err != nil {
 		return err
 	}
 	addr := net.JoinHostPort(c.host, c.port)
@@ -51,3 +52,18 @@ func (c *crashOperator) Execute() error {
 	}
 	return nil
 }
+
+// Recover restores Restart=always and starts the service again.
+func (c *crashOperator) Recover() error {
+	if c.service == "" {
+		return nil
+	}
+	if _, err := ssh.S.Systemd(c.host, ssh.Always, c.service); err != nil {
+		return err
+	}
+	if _, err := ssh.S.Systemctl(c.host, ssh.Start, c.service); err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] started %s", crash, net.JoinHostPort(c.host, c.port))
+	return nil
+}
@@ -3,6 +3,7 @@ package operator
 import (
 	"fmt"
 	"net"
+	"path/filepath"
 	"pictorial/comp"
 	"pictorial/log"
 	"pictorial/ssh"
@@ -15,6 +16,8 @@ type dataCorruptedOperator struct {
 	port       string
 	cType      comp.CType
 	deployPath string
+	dataPath   string
+	bakPath    string
 }
 
 func (d *dataCorruptedOperator) Execute() error {
@@ -27,7 +30,32 @@ func (d *dataCorruptedOperator) Execute() error {
 	if _, err := ssh.S.Mv(d.host, dataPath, bakName); err != nil {
 		return err
 	}
+	d.dataPath, d.bakPath = dataPath, bakName
 	addr := net.JoinHostPort(d.host, d.port)
 	log.Logger.Infof("[%s] [%s] [%s] [%s] to [%s].", dataCorrupted, cType, addr, dataPath, bakName)
 	return nil
 }
+
+// Recover stops the instance and moves the data dir back, the data written to the new dir is dropped.
+func (d *dataCorruptedOperator) Recover() error {
+	if d.bakPath == "" {
+		return nil
+	}
+	cType := comp.GetCTypeValue(d.cType)
+	service := filepath.Join(systemdPath, fmt.Sprintf(serviceFile, cType, d.port))
+	if _, err := ssh.S.Systemctl(d.host, ssh.Stop, service); err != nil {
+		return err
+	}
+	if _, err := ssh.S.Remove(d.host, d.dataPath); err != nil {
+		return err
+	}
+	if _, err := ssh.S.Mv(d.host, d.bakPath, d.dataPath); err != nil {
+		return err
+	}
+	if _, err := ssh.S.Systemctl(d.host, ssh.Start, service); err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] [%s] [%s] restored [%s].", dataCorrupted, cType, net.JoinHostPort(d.host, d.port), d.dataPath)
+	d.bakPath = ""
+	return nil
+}
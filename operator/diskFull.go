@@ -47,14 +47,12 @@ func (d *diskFullOperator) Execute() error {
 			log.Logger.Error(err)
 		}
 	}()
-	go func() {
-		select {
-		case <-d.ctx.Done():
-			if err := d.aftercare(dataPath); err != nil {
-				log.Logger.Errorf("[disk_full] aftercare failed: %s", err.Error())
-			}
+	goAftercare(func() {
+		<-d.ctx.Done()
+		if err := d.aftercare(dataPath); err != nil {
+			log.Logger.Errorf("[disk_full] aftercare failed: %s", err.Error())
 		}
-	}()
+	})
 	return nil
 }
 
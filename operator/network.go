@@ -0,0 +1,217 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/ssh"
+	"strings"
+	"sync"
+)
+
+type Netem struct {
+	Delay  string
+	Jitter string
+	Loss   string
+}
+
+var Network = Netem{
+	Delay:  "200ms",
+	Jitter: "50ms",
+	Loss:   "0%",
+}
+
+func (n Netem) String() string {
+	var args []string
+	if n.Delay != "" {
+		args = append(args, "delay", n.Delay)
+		if n.Jitter != "" {
+			args = append(args, n.Jitter)
+		}
+	}
+	if n.Loss != "" && strings.TrimSuffix(n.Loss, "%") != "0" {
+		args = append(args, "loss", n.Loss)
+	}
+	return strings.Join(args, " ")
+}
+
+// networkIsolationOperator drops the traffic between the host and its peers,
+// the partition by label is the same with the other side as peers.
+type networkIsolationOperator struct {
+	oType OType
+	host  string
+	port  string
+	cType comp.CType
+	peers []string
+	ctx   context.Context
+	// mu guards isolated, the rules which are not removed yet.
+	mu       sync.Mutex
+	isolated []iptablesRule
+	done     chan struct{}
+	once     sync.Once
+}
+
+// iptablesRule is a rule dropping the traffic with the peer in one chain.
+type iptablesRule struct {
+	chain string
+	peer  string
+}
+
+func (r iptablesRule) String() string {
+	return fmt.Sprintf("%s %s", r.chain, r.peer)
+}
+
+func (n *networkIsolationOperator) Execute() error {
+	ov := GetOTypeValue(n.oType)
+	cType := comp.GetCTypeValue(n.cType)
+	if len(n.peers) == 0 {
+		log.Logger.Warnf("[%s] [%s] %s has no peer, skip.", ov, cType, n.host)
+		return nil
+	}
+	var err error
+	n.mu.Lock()
+	// every rule added is tracked, so a half isolated peer is recovered as well.
+peers:
+	for _, p := range n.peers {
+		for _, chain := range ssh.IptablesChains {
+			if _, err = ssh.S.IptablesDrop(n.host, chain, p); err != nil {
+				break peers
+			}
+			n.isolated = append(n.isolated, iptablesRule{chain: chain, peer: p})
+		}
+	}
+	n.mu.Unlock()
+	n.done = make(chan struct{})
+	goAftercare(n.aftercare)
+	if err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] [%s] [%s] from %v", ov, cType, n.host, n.peers)
+	return nil
+}
+
+// aftercare removes the rules left when the job is cancelled, e.g. the case failed before its recovery.
+func (n *networkIsolationOperator) aftercare() {
+	select {
+	case <-n.ctx.Done():
+		if err := n.Recover(); err != nil {
+			log.Logger.Errorf("[%s] aftercare failed: %s", GetOTypeValue(n.oType), err.Error())
+		}
+	case <-n.done:
+	}
+}
+
+// Recover removes the iptables rules between the host and the peers, the ones which fail are kept for the aftercare.
+func (n *networkIsolationOperator) Recover() error {
+	ov := GetOTypeValue(n.oType)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var left []iptablesRule
+	for _, r := range n.isolated {
+		if _, err := ssh.S.IptablesRecover(n.host, r.chain, r.peer); err != nil {
+			log.Logger.Errorf("[%s] recover %s - %s failed: %s", ov, n.host, r, err.Error())
+			left = append(left, r)
+		}
+	}
+	if len(left) != 0 {
+		n.isolated = left
+		return fmt.Errorf("[%s] recover %s from %v failed", ov, n.host, left)
+	}
+	if len(n.isolated) != 0 {
+		log.Logger.Infof("[%s] recovered [%s] from %v", ov, n.host, n.isolated)
+	}
+	n.isolated = nil
+	if n.done != nil {
+		n.once.Do(func() { close(n.done) })
+	}
+	return nil
+}
+
+type networkDelayOperator struct {
+	host  string
+	port  string
+	cType comp.CType
+	peers []string
+	ctx   context.Context
+	// mu guards devices, the devices whose qdisc is not deleted yet.
+	mu      sync.Mutex
+	devices []string
+	done    chan struct{}
+	once    sync.Once
+}
+
+const networkDelay = "network_delay"
+
+func (n *networkDelayOperator) Execute() error {
+	cType := comp.GetCTypeValue(n.cType)
+	if len(n.peers) == 0 {
+		log.Logger.Warnf("[%s] [%s] %s has no peer, skip.", networkDelay, cType, n.host)
+		return nil
+	}
+	devices := make(map[string][]string)
+	for _, p := range n.peers {
+		dev, err := ssh.S.NetDevice(n.host, p)
+		if err != nil {
+			return err
+		}
+		devices[dev] = append(devices[dev], p)
+	}
+	netem := Network.String()
+	if netem == "" {
+		return fmt.Errorf("[%s] delay, jitter and loss are all empty", networkDelay)
+	}
+	var err error
+	n.mu.Lock()
+	for dev, peers := range devices {
+		// a qdisc added partly is deleted as well.
+		n.devices = append(n.devices, dev)
+		if _, err = ssh.S.NetemAdd(n.host, dev, netem, peers); err != nil {
+			break
+		}
+	}
+	n.mu.Unlock()
+	n.done = make(chan struct{})
+	goAftercare(n.aftercare)
+	if err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] [%s] [%s] %s to %v", networkDelay, cType, n.host, netem, n.peers)
+	return nil
+}
+
+// aftercare deletes the qdisc left when the job is cancelled, e.g. the case failed before its recovery.
+func (n *networkDelayOperator) aftercare() {
+	select {
+	case <-n.ctx.Done():
+		if err := n.Recover(); err != nil {
+			log.Logger.Errorf("[%s] aftercare failed: %s", networkDelay, err.Error())
+		}
+	case <-n.done:
+	}
+}
+
+// Recover deletes the netem qdisc of the devices, the ones which fail are kept for the aftercare.
+func (n *networkDelayOperator) Recover() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var left []string
+	for _, dev := range n.devices {
+		if _, err := ssh.S.NetemDel(n.host, dev); err != nil {
+			log.Logger.Errorf("[%s] recover %s %s failed: %s", networkDelay, n.host, dev, err.Error())
+			left = append(left, dev)
+		}
+	}
+	if len(left) != 0 {
+		n.devices = left
+		return fmt.Errorf("[%s] recover %s %v failed", networkDelay, n.host, left)
+	}
+	if len(n.devices) != 0 {
+		log.Logger.Infof("[%s] recovered [%s] %v", networkDelay, n.host, n.devices)
+	}
+	n.devices = nil
+	if n.done != nil {
+		n.once.Do(func() { close(n.done) })
+	}
+	return nil
+}
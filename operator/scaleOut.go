@@ -0,0 +1,249 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/ssh"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+type ScaleOutConfig struct {
+	Host string
+	// Template is the path of a tiup topology template, the built-in one of the component is used if empty.
+	Template string
+	Timeout  time.Duration
+}
+
+var ScaleOutTopology = ScaleOutConfig{
+	Timeout: 10 * time.Minute,
+}
+
+// topology is the data of the template, the ports are shifted by portStep until none of them is used on the host.
+type topology struct {
+	Host        string
+	Port        int
+	StatusPort  int
+	PeerPort    int
+	TCPPort     int
+	ProxyPort   int
+	MetricsPort int
+}
+
+const portStep = 100
+
+var defaultPorts = map[comp.CType]topology{
+	comp.TiKV:    {Port: 20160, StatusPort: 20180},
+	comp.TiDB:    {Port: 4000, StatusPort: 10080},
+	comp.PD:      {Port: 2379, PeerPort: 2380},
+	comp.TiFlash: {Port: 3930, StatusPort: 20292, TCPPort: 9000, ProxyPort: 20170, MetricsPort: 8234},
+}
+
+var defaultTemplates = map[comp.CType]string{
+	comp.TiKV: `tikv_servers:
+  - host: {{.Host}}
+    port: {{.Port}}
+    status_port: {{.StatusPort}}
+`,
+	comp.TiDB: `tidb_servers:
+  - host: {{.Host}}
+    port: {{.Port}}
+    status_port: {{.StatusPort}}
+`,
+	comp.PD: `pd_servers:
+  - host: {{.Host}}
+    client_port: {{.Port}}
+    peer_port: {{.PeerPort}}
+`,
+	comp.TiFlash: `tiflash_servers:
+  - host: {{.Host}}
+    tcp_port: {{.TCPPort}}
+    flash_service_port: {{.Port}}
+    flash_proxy_port: {{.ProxyPort}}
+    flash_proxy_status_port: {{.StatusPort}}
+    metrics_port: {{.MetricsPort}}
+`,
+}
+
+type scaleOutOperator struct {
+	host     string
+	cType    comp.CType
+	topology string
+	ctx      context.Context
+}
+
+const scaleOut = "scale_out"
+
+const scaleOutCheckInterval = 5 * time.Second
+
+func (s *scaleOutOperator) Execute() error {
+	cType := comp.GetCTypeValue(s.cType)
+	if s.host == "" {
+		return fmt.Errorf("[%s] config [scaleOut.host] must not be empty", scaleOut)
+	}
+	t, err := s.newTopology()
+	if err != nil {
+		return err
+	}
+	if err := s.writeTopology(t); err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	log.Logger.Infof("[%s] [%s] %s ...", scaleOut, cType, addr)
+	if _, err := ssh.S.ScaleOut(s.topology); err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] [%s] %s deployed, wait for serving", scaleOut, cType, addr)
+	if err := s.wait(t); err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] [%s] %s complete", scaleOut, cType, addr)
+	return nil
+}
+
+// newTopology shifts the ports until none of them is used on the host, the ports of every instance of the cluster
+// on the host and the ones listened there are taken as used.
+func (s *scaleOutOperator) newTopology() (topology, error) {
+	t, ok := defaultPorts[s.cType]
+	if !ok {
+		return t, fmt.Errorf("[%s] unsupported component: %s", scaleOut, comp.GetCTypeValue(s.cType))
+	}
+	t.Host = s.host
+	c, err := comp.New()
+	if err != nil {
+		return t, err
+	}
+	used := make(map[int]bool)
+	for _, cs := range c.Map {
+		for _, e := range cs {
+			if e.Host != t.Host {
+				continue
+			}
+			for _, p := range []string{comp.CleanLeaderFlag(e.Port), e.StatusPort} {
+				if port, err := strconv.Atoi(p); err == nil {
+					used[port] = true
+				}
+			}
+		}
+	}
+	listening, err := ssh.S.ListeningPorts(t.Host)
+	if err != nil {
+		log.Logger.Warnf("[%s] get the listening ports of %s failed: %s", scaleOut, t.Host, err.Error())
+	}
+	for _, port := range listening {
+		used[port] = true
+	}
+	for t.collides(used) {
+		t.shift()
+		if t.maxPort() > maxPort {
+			return t, fmt.Errorf("[%s] no free ports on %s", scaleOut, t.Host)
+		}
+	}
+	return t, nil
+}
+
+const maxPort = 65535
+
+func (t *topology) ports() []*int {
+	var ports []*int
+	for _, p := range []*int{&t.Port, &t.StatusPort, &t.PeerPort, &t.TCPPort, &t.ProxyPort, &t.MetricsPort} {
+		if *p != 0 {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+func (t *topology) collides(used map[int]bool) bool {
+	for _, p := range t.ports() {
+		if used[*p] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *topology) maxPort() int {
+	var m int
+	for _, p := range t.ports() {
+		if *p > m {
+			m = *p
+		}
+	}
+	return m
+}
+
+func (t *topology) shift() {
+	for _, p := range t.ports() {
+		*p += portStep
+	}
+}
+
+func (s *scaleOutOperator) writeTopology(t topology) error {
+	var tpl *template.Template
+	var err error
+	if ScaleOutTopology.Template != "" {
+		tpl, err = template.ParseFiles(ScaleOutTopology.Template)
+	} else {
+		tpl, err = template.New(scaleOut).Parse(defaultTemplates[s.cType])
+	}
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(s.topology)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return tpl.Execute(f, t)
+}
+
+// wait blocks until the new instance is serving, for tikv the regions should start balancing to it.
+func (s *scaleOutOperator) wait(t topology) error {
+	cType := comp.GetCTypeValue(s.cType)
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	timeout := time.After(ScaleOutTopology.Timeout)
+	ticker := time.NewTicker(scaleOutCheckInterval)
+	defer ticker.Stop()
+	for {
+		ready, err := s.ready(t)
+		if err != nil {
+			log.Logger.Debugf("[%s] [%s] %s: %s", scaleOut, cType, addr, err.Error())
+		}
+		if ready {
+			return nil
+		}
+		select {
+		case <-s.ctx.Done():
+			return fmt.Errorf("[%s] [%s] %s cancelled", scaleOut, cType, addr)
+		case <-timeout:
+			return fmt.Errorf("[%s] [%s] %s is not ready in %s", scaleOut, cType, addr, ScaleOutTopology.Timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scaleOutOperator) ready(t topology) (bool, error) {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	switch s.cType {
+	case comp.TiKV, comp.TiFlash:
+		st, err := comp.GetStoreStatus(addr)
+		if err != nil {
+			return false, err
+		}
+		log.Logger.Infof("[%s] [%s] %s state: %s, region: %d, leader: %d", scaleOut, comp.GetCTypeValue(s.cType), addr, st.StateName, st.RegionCount, st.LeaderCount)
+		// tiflash only has the regions of the tables with tiflash replica
+		return st.StateName == comp.StoreUp && (s.cType == comp.TiFlash || st.RegionCount > 0), nil
+	case comp.PD:
+		return comp.IsPDMember(addr)
+	case comp.TiDB:
+		_, err := comp.GetTiDBStatus(net.JoinHostPort(t.Host, strconv.Itoa(t.StatusPort)))
+		return err == nil, err
+	}
+	return false, nil
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"github.com/pelletier/go-toml"
 	"github.com/sirupsen/logrus"
+	"pictorial/bench"
 	"pictorial/comp"
 	"pictorial/log"
 	"pictorial/mysql"
+	"pictorial/operator"
 	"pictorial/server/job"
 	"pictorial/ssh"
 	"pictorial/widget"
@@ -25,18 +27,95 @@ const (
 	sshUser     = "ssh.user"
 	sshPassword = "ssh.password"
 	sshPort     = "ssh.sshPort"
+	sshKey      = "ssh.key"
+	sshAgent    = "ssh.agent"
+	sshTopology = "ssh.topology"
+	// sshConcurrency is the hosts which run a command at once, e.g. the tikvs crashed by a disaster
+	sshConcurrency = "ssh.concurrency"
+	// sshHostKeyMode is strict, tofu or insecure, tofu records the key of a host on the first login
+	sshHostKeyMode = "ssh.host_key"
+	sshKnownHosts  = "ssh.known_hosts"
+	// sshHost is [[ssh.host]], the credential of a host overriding the defaults
+	sshHost         = "ssh.host"
+	sshHostHost     = "host"
+	sshHostUser     = "user"
+	sshHostPort     = "port"
+	sshHostKey      = "key"
+	sshHostAgent    = "agent"
+	sshHostPassword = "password"
 
-	clusterName  = "cluster.name"
-	loadCmd      = "load.cmd"
-	loadInterval = "load.interval"
-	loadSleep    = "load.sleep"
-	logLevel     = "log.level"
-	otherDir     = "other.dir"
+	clusterName = "cluster.name"
+	logLevel    = "log.level"
+	otherDir    = "other.dir"
+
+	// load is a [load] table or [[load]] tables, the keys are read from each of them.
+	load         = "load"
+	loadName     = "name"
+	loadCmd      = "cmd"
+	loadRestart  = "restart"
+	loadInterval = "interval"
+	loadSleep    = "sleep"
+	// the built-in workload instead of the cmd, test is one of the oltp tests, tpcc, tpch or htap
+	loadTest       = "test"
+	loadDB         = "db"
+	loadTables     = "tables"
+	loadTableSize  = "table_size"
+	loadThreads    = "threads"
+	loadTime       = "time"
+	loadWarehouses = "warehouses"
+	loadScale      = "scale"
+	loadQueries    = "queries"
+	loadCheck      = "check"
+	loadReplicas   = "replicas"
+	loadAPThreads  = "ap_threads"
+
+	htapDB        = "htap.db"
+	htapReplicas  = "htap.replicas"
+	htapRows      = "htap.rows"
+	htapThreads   = "htap.threads"
+	htapAPThreads = "htap.ap_threads"
+	htapTime      = "htap.time"
+
+	networkDelay  = "network.delay"
+	networkJitter = "network.jitter"
+	networkLoss   = "network.loss"
+
+	scaleOutHost     = "scaleOut.host"
+	scaleOutTemplate = "scaleOut.template"
+	scaleOutTimeout  = "scaleOut.timeout"
+
+	recoverTimeout = "recover.timeout"
+
+	// logs are collected into the result dir at the end of a job
+	logsEnable     = "logs.enable"
+	logsPadding    = "logs.padding"
+	logsComponents = "logs.components"
+	logsCheck      = "logs.check"
+	// logsRule is [[logs.rule]], a rule replaces the built-in one with the same name
+	logsRule           = "logs.rule"
+	logsRuleName       = "name"
+	logsRulePattern    = "pattern"
+	logsRuleAction     = "action"
+	logsRuleComponents = "components"
+	logsRuleCases      = "cases"
+	logsRuleTargets    = "targets"
+
+	probeEnable   = "probe.enable"
+	probeInterval = "probe.interval"
+	probeTimeout  = "probe.timeout"
+	probeWindow   = "probe.window"
+
+	metricsSource  = "metrics.source"
+	metricsRange   = "metrics.range"
+	metricsStep    = "metrics.step"
+	metricsPadding = "metrics.padding"
+	metricsPanel   = "metrics.panel"
+	metricsTZ      = "metrics.tz"
+	metricsSets    = "metrics.sets"
 )
 
 var notNil = []string{
 	mysqlHost, mysqlPort, mysqlUser, mysqlPassword,
-	sshUser, sshPort,
 	clusterName,
 }
 
@@ -68,11 +147,9 @@ func initConfig(cfg *toml.Tree) error {
 		return err
 	}
 
-	ssh.S.User = cfg.Get(sshUser).(string)
-	if cfg.Get(sshPassword) != nil {
-		ssh.S.Password = cfg.Get(sshPassword).(string)
+	if err := initSSH(cfg); err != nil {
+		return err
 	}
-	ssh.S.SshPort = cfg.Get(sshPort).(string)
 	ssh.S.Cluster.Name = cfg.Get(clusterName).(string)
 	ssh.S.LogC = make(chan string)
 	if err := ssh.S.CheckClusterName(); err != nil {
@@ -81,14 +158,122 @@ func initConfig(cfg *toml.Tree) error {
 	if err := ssh.S.AddSSHKey(); err != nil {
 		return err
 	}
-	if cfg.Get(loadCmd) != nil {
-		job.Ld.Cmd = cfg.Get(loadCmd).(string)
+	if err := ssh.S.InitHostKeys(); err != nil {
+		return err
+	}
+	switch ld := cfg.Get(load).(type) {
+	case *toml.Tree:
+		if err := initLoad(ld, false); err != nil {
+			return err
+		}
+	case []*toml.Tree:
+		for _, t := range ld {
+			if err := initLoad(t, true); err != nil {
+				return err
+			}
+		}
+	}
+	if cfg.Get(networkDelay) != nil {
+		operator.Network.Delay = cfg.Get(networkDelay).(string)
+	}
+	if cfg.Get(networkJitter) != nil {
+		operator.Network.Jitter = cfg.Get(networkJitter).(string)
+	}
+	if cfg.Get(networkLoss) != nil {
+		operator.Network.Loss = cfg.Get(networkLoss).(string)
+	}
+	if cfg.Get(scaleOutHost) != nil {
+		operator.ScaleOutTopology.Host = cfg.Get(scaleOutHost).(string)
+	}
+	if cfg.Get(scaleOutTemplate) != nil {
+		operator.ScaleOutTopology.Template = cfg.Get(scaleOutTemplate).(string)
+	}
+	if cfg.Get(scaleOutTimeout) != nil {
+		operator.ScaleOutTopology.Timeout = time.Duration(cfg.Get(scaleOutTimeout).(int64)) * time.Second
+	}
+	if cfg.Get(recoverTimeout) != nil {
+		job.RecoverTimeout = time.Duration(cfg.Get(recoverTimeout).(int64)) * time.Second
+	}
+	if cfg.Get(htapDB) != nil {
+		job.HtapCase.DB = cfg.Get(htapDB).(string)
 	}
-	if cfg.Get(loadInterval) != nil {
-		job.Ld.Interval = cfg.Get(loadInterval).(int64)
+	if cfg.Get(htapReplicas) != nil {
+		job.HtapCase.Replicas = int(cfg.Get(htapReplicas).(int64))
 	}
-	if cfg.Get(loadSleep) != nil {
-		job.Ld.Sleep = time.Duration(cfg.Get(loadSleep).(int64))
+	if cfg.Get(htapRows) != nil {
+		job.HtapCase.Rows = int(cfg.Get(htapRows).(int64))
+	}
+	if cfg.Get(htapThreads) != nil {
+		job.HtapCase.Threads = int(cfg.Get(htapThreads).(int64))
+	}
+	if cfg.Get(htapAPThreads) != nil {
+		job.HtapCase.APThreads = int(cfg.Get(htapAPThreads).(int64))
+	}
+	if cfg.Get(htapTime) != nil {
+		job.HtapCase.Duration = time.Duration(cfg.Get(htapTime).(int64)) * time.Second
+	}
+	if cfg.Get(logsEnable) != nil {
+		job.Logs.Enable = cfg.Get(logsEnable).(bool)
+	}
+	if cfg.Get(logsPadding) != nil {
+		job.Logs.Padding = time.Duration(cfg.Get(logsPadding).(int64)) * time.Second
+	}
+	if cfg.Get(logsComponents) != nil {
+		job.Logs.Components = nil
+		for _, c := range cfg.Get(logsComponents).([]interface{}) {
+			job.Logs.Components = append(job.Logs.Components, fmt.Sprint(c))
+		}
+	}
+	if cfg.Get(logsCheck) != nil {
+		job.Logs.Check = cfg.Get(logsCheck).(bool)
+	}
+	if err := initLogRules(cfg); err != nil {
+		return err
+	}
+	if cfg.Get(probeEnable) != nil {
+		job.Pb.Enable = cfg.Get(probeEnable).(bool)
+	}
+	if cfg.Get(probeInterval) != nil {
+		job.Pb.Interval = time.Duration(cfg.Get(probeInterval).(int64)) * time.Millisecond
+	}
+	if cfg.Get(probeTimeout) != nil {
+		job.Pb.Timeout = time.Duration(cfg.Get(probeTimeout).(int64)) * time.Millisecond
+	}
+	if cfg.Get(probeWindow) != nil {
+		job.Pb.Window = time.Duration(cfg.Get(probeWindow).(int64)) * time.Millisecond
+	}
+	if cfg.Get(metricsSource) != nil {
+		comp.Metrics.Source = cfg.Get(metricsSource).(string)
+		if comp.Metrics.Source != comp.SourcePrometheus && comp.Metrics.Source != comp.SourceGrafana {
+			return fmt.Errorf("config [%s] must be %s or %s", metricsSource, comp.SourcePrometheus, comp.SourceGrafana)
+		}
+	}
+	if cfg.Get(metricsRange) != nil {
+		comp.Metrics.Range = time.Duration(cfg.Get(metricsRange).(int64)) * time.Second
+	}
+	if cfg.Get(metricsStep) != nil {
+		comp.Metrics.Step = time.Duration(cfg.Get(metricsStep).(int64)) * time.Second
+	}
+	if cfg.Get(metricsPadding) != nil {
+		comp.Metrics.Padding = time.Duration(cfg.Get(metricsPadding).(int64)) * time.Second
+	}
+	if cfg.Get(metricsPanel) != nil {
+		comp.Metrics.Panel = cfg.Get(metricsPanel).(string)
+	}
+	if cfg.Get(metricsTZ) != nil {
+		comp.Metrics.TZ = cfg.Get(metricsTZ).(string)
+	}
+	if sets, ok := cfg.Get(metricsSets).(*toml.Tree); ok {
+		// the keys may be catalog indexes with dots, so they are read from the map instead of cfg.Get
+		for k, v := range sets.ToMap() {
+			names, ok := v.([]interface{})
+			if !ok {
+				return fmt.Errorf("config [%s.%s] must be an array of panel names", metricsSets, k)
+			}
+			for _, n := range names {
+				comp.Metrics.Sets[k] = append(comp.Metrics.Sets[k], fmt.Sprint(n))
+			}
+		}
 	}
 
 	if cfg.Get(logLevel) != nil {
@@ -104,3 +289,264 @@ func initConfig(cfg *toml.Tree) error {
 
 	return nil
 }
+
+// initSSH reads the default credential, the overrides of the hosts and the topology,
+// the user is the one of the topology if ssh.user is not set.
+func initSSH(cfg *toml.Tree) error {
+	if cfg.Get(sshUser) != nil {
+		ssh.S.User = cfg.Get(sshUser).(string)
+	}
+	if cfg.Get(sshPassword) != nil {
+		ssh.S.Password = cfg.Get(sshPassword).(string)
+	}
+	if cfg.Get(sshPort) != nil {
+		ssh.S.SshPort = cfg.Get(sshPort).(string)
+	}
+	if cfg.Get(sshKey) != nil {
+		ssh.S.KeyPath = cfg.Get(sshKey).(string)
+	}
+	if cfg.Get(sshAgent) != nil {
+		ssh.S.Agent = cfg.Get(sshAgent).(bool)
+	}
+	if cfg.Get(sshHostKeyMode) != nil {
+		ssh.S.HostKey = cfg.Get(sshHostKeyMode).(string)
+	}
+	if cfg.Get(sshKnownHosts) != nil {
+		ssh.S.KnownHosts = cfg.Get(sshKnownHosts).(string)
+	}
+	if cfg.Get(sshConcurrency) != nil {
+		ssh.S.Concurrency = int(cfg.Get(sshConcurrency).(int64))
+	}
+	if cfg.Get(sshTopology) != nil && cfg.Get(sshTopology).(string) != "" {
+		t, err := ssh.LoadTopology(cfg.Get(sshTopology).(string))
+		if err != nil {
+			return err
+		}
+		ssh.S.Topology = t
+	}
+	if ssh.S.User == "" && ssh.S.Topology == nil {
+		return fmt.Errorf("config [%s] must not be empty without [%s]", sshUser, sshTopology)
+	}
+	hosts, _ := cfg.Get(sshHost).([]*toml.Tree)
+	for _, t := range hosts {
+		if t.Get(sshHostHost) == nil {
+			return fmt.Errorf("config [[%s]] %s must not be empty", sshHost, sshHostHost)
+		}
+		var c ssh.Credential
+		if t.Get(sshHostUser) != nil {
+			c.User = t.Get(sshHostUser).(string)
+		}
+		if t.Get(sshHostPort) != nil {
+			c.Port = fmt.Sprint(t.Get(sshHostPort))
+		}
+		if t.Get(sshHostKey) != nil {
+			c.KeyPath = t.Get(sshHostKey).(string)
+		}
+		if t.Get(sshHostAgent) != nil {
+			c.Agent = t.Get(sshHostAgent).(bool)
+		}
+		if t.Get(sshHostPassword) != nil {
+			c.Password = t.Get(sshHostPassword).(string)
+		}
+		if ssh.S.Hosts == nil {
+			ssh.S.Hosts = make(map[string]ssh.Credential)
+		}
+		ssh.S.Hosts[t.Get(sshHostHost).(string)] = c
+	}
+	return nil
+}
+
+// initLogRules adds the rules of [[logs.rule]], a rule with the name of a built-in one replaces it.
+func initLogRules(cfg *toml.Tree) error {
+	rules, _ := cfg.Get(logsRule).([]*toml.Tree)
+	strs := func(t *toml.Tree, key string) []string {
+		var vs []string
+		if vals, ok := t.Get(key).([]interface{}); ok {
+			for _, v := range vals {
+				vs = append(vs, fmt.Sprint(v))
+			}
+		}
+		return vs
+	}
+	for _, t := range rules {
+		if t.Get(logsRuleName) == nil || t.Get(logsRulePattern) == nil {
+			return fmt.Errorf("config [[%s]] %s and %s must not be empty", logsRule, logsRuleName, logsRulePattern)
+		}
+		r := job.LogRule{
+			Name:       t.Get(logsRuleName).(string),
+			Pattern:    t.Get(logsRulePattern).(string),
+			Action:     job.RuleFail,
+			Components: strs(t, logsRuleComponents),
+			Cases:      strs(t, logsRuleCases),
+			Targets:    strs(t, logsRuleTargets),
+		}
+		if t.Get(logsRuleAction) != nil {
+			r.Action = t.Get(logsRuleAction).(string)
+		}
+		switch r.Action {
+		case job.RuleFail, job.RuleExpect, job.RuleCount:
+		default:
+			return fmt.Errorf("config [[%s]] %s: %s must be %s, %s or %s", logsRule, r.Name, logsRuleAction, job.RuleFail, job.RuleExpect, job.RuleCount)
+		}
+		replaced := false
+		for i := range job.LogRules {
+			if job.LogRules[i].Name == r.Name {
+				job.LogRules[i] = r
+				replaced = true
+			}
+		}
+		if !replaced {
+			job.LogRules = append(job.LogRules, r)
+		}
+	}
+	return nil
+}
+
+// initLoad adds the workload of the table, interval and sleep are shared by all of the workloads.
+func initLoad(t *toml.Tree, named bool) error {
+	if t.Get(loadInterval) != nil {
+		job.Ld.Interval = t.Get(loadInterval).(int64)
+	}
+	if t.Get(loadSleep) != nil {
+		job.Ld.Sleep = time.Duration(t.Get(loadSleep).(int64))
+	}
+	w := &job.Workload{
+		Restart: job.RestartNo,
+	}
+	if t.Get(loadCmd) != nil {
+		w.Cmd = t.Get(loadCmd).(string)
+	}
+	if t.Get(loadTest) != nil {
+		if w.Cmd != "" {
+			return fmt.Errorf("config [%s] only one of %s and %s is allowed", load, loadCmd, loadTest)
+		}
+		r, err := initRunner(t)
+		if err != nil {
+			return err
+		}
+		w.Runner = r
+	}
+	if t.Get(loadCheck) != nil {
+		w.Check = t.Get(loadCheck).(bool)
+	}
+	if w.Cmd == "" && w.Runner == nil {
+		return nil
+	}
+	if named {
+		if t.Get(loadName) == nil || t.Get(loadName).(string) == "" {
+			return fmt.Errorf("config [[%s]] %s must not be empty", load, loadName)
+		}
+		w.Name = t.Get(loadName).(string)
+		for _, o := range job.Ld.Workloads {
+			if o.Name == w.Name {
+				return fmt.Errorf("config [[%s]] %s is duplicated", load, w.Name)
+			}
+		}
+	}
+	if t.Get(loadRestart) != nil {
+		w.Restart = t.Get(loadRestart).(string)
+		switch w.Restart {
+		case job.RestartNo, job.RestartOnFailure, job.RestartAlways:
+		default:
+			return fmt.Errorf("config [%s.%s] must be %s, %s or %s", load, loadRestart, job.RestartNo, job.RestartOnFailure, job.RestartAlways)
+		}
+	}
+	job.Ld.Workloads = append(job.Ld.Workloads, w)
+	return nil
+}
+
+// initRunner reads the built-in workload, the defaults are the same as the online ddl, tpcc and htap cases.
+func initRunner(t *toml.Tree) (bench.Runner, error) {
+	test := t.Get(loadTest).(string)
+	db := "poc"
+	if t.Get(loadDB) != nil {
+		db = t.Get(loadDB).(string)
+	}
+	threads := 5
+	if t.Get(loadThreads) != nil {
+		threads = int(t.Get(loadThreads).(int64))
+	}
+	var d time.Duration
+	if t.Get(loadTime) != nil {
+		d = time.Duration(t.Get(loadTime).(int64)) * time.Second
+	}
+	switch test {
+	case "tpcc":
+		r := &bench.Tpcc{
+			Mysql:      mysql.M,
+			DB:         db,
+			Warehouses: 10,
+			Threads:    threads,
+			Cmd:        bench.CmdRun,
+			Duration:   d,
+		}
+		if t.Get(loadWarehouses) != nil {
+			r.Warehouses = int(t.Get(loadWarehouses).(int64))
+		}
+		return r, nil
+	case "tpch":
+		r := &bench.Tpch{
+			Mysql:    mysql.M,
+			DB:       db,
+			Scale:    1,
+			Threads:  threads,
+			Cmd:      bench.CmdRun,
+			Duration: d,
+		}
+		switch v := t.Get(loadScale).(type) {
+		case int64:
+			r.Scale = float64(v)
+		case float64:
+			r.Scale = v
+		}
+		if t.Get(loadQueries) != nil {
+			for _, q := range t.Get(loadQueries).([]interface{}) {
+				r.Queries = append(r.Queries, fmt.Sprint(q))
+			}
+		}
+		return r, nil
+	case "htap":
+		r := &bench.Htap{
+			Mysql:     mysql.M,
+			DB:        db,
+			Replicas:  1,
+			Rows:      100000,
+			Threads:   threads,
+			APThreads: 2,
+			Cmd:       bench.CmdRun,
+			Duration:  d,
+		}
+		if t.Get(loadReplicas) != nil {
+			r.Replicas = int(t.Get(loadReplicas).(int64))
+		}
+		if t.Get(loadTableSize) != nil {
+			r.Rows = int(t.Get(loadTableSize).(int64))
+		}
+		if t.Get(loadAPThreads) != nil {
+			r.APThreads = int(t.Get(loadAPThreads).(int64))
+		}
+		return r, nil
+	}
+	tp, ok := bench.GetSysbenchTp(test)
+	if !ok {
+		return nil, fmt.Errorf("config [%s.%s] %s is not one of %s, %s, %s, tpcc, tpch and htap", load, loadTest, test,
+			bench.GetSysbenchTpValue(bench.OltpReadWrite), bench.GetSysbenchTpValue(bench.OltpInsert), bench.GetSysbenchTpValue(bench.OltpPointSelect))
+	}
+	o := &bench.Oltp{
+		Test:      tp,
+		Mysql:     mysql.M,
+		Db:        db,
+		TableSize: 1000000,
+		Tables:    1,
+		Threads:   threads,
+		Cmd:       bench.CmdRun,
+		Duration:  d,
+	}
+	if t.Get(loadTables) != nil {
+		o.Tables = int(t.Get(loadTables).(int64))
+	}
+	if t.Get(loadTableSize) != nil {
+		o.TableSize = int(t.Get(loadTableSize).(int64))
+	}
+	return o, nil
+}
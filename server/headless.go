@@ -0,0 +1,125 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pelletier/go-toml"
+	"os"
+	"pictorial/log"
+	"pictorial/server/job"
+	"pictorial/widget"
+	"time"
+)
+
+const RunCommand = "run"
+
+const (
+	exitPass = 0
+	exitFail = 1
+)
+
+// Run executes the selected catalog cases without the termui interface,
+// e.g. tipoc run --cases "1.2.*,2.3,7.2:tikv/10.0.0.1:20160",
+// the transcripts of the scripts are written as golden outputs into the dir of --record instead of diffed.
+func Run(args []string) int {
+
+	log.New(logName)
+
+	var cfgPath, cases, record string
+	fs := flag.NewFlagSet(RunCommand, flag.ExitOnError)
+	fs.StringVar(&cfgPath, "c", defaultCfg, "config file")
+	fs.StringVar(&cases, "cases", "", "catalog index or glob, separated by ','")
+	fs.StringVar(&record, "record", "", "dir to record the golden outputs of the scripts into")
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(err)
+		return exitFail
+	}
+
+	go printLog()
+
+	if cases == "" {
+		log.Logger.Error("--cases must not be empty")
+		return finish(exitFail)
+	}
+	cfg, err := toml.LoadFile(cfgPath)
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	if err := initConfig(cfg); err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	if record != "" {
+		if err := os.MkdirAll(record, os.ModePerm); err != nil {
+			log.Logger.Error(err)
+			return finish(exitFail)
+		}
+		job.RecordPath = record
+	}
+	tree, err := widget.NewTree()
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	selected, err := widget.Select(tree, cases)
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	w := widget.Widget{
+		T: tree,
+		S: selected,
+	}
+	examples, err := w.WalkTreeScript()
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+
+	j := job.New(examples, selected)
+	go j.Run()
+	return wait(&j, widget.TreeLength(selected))
+}
+
+// wait prints the progress and the load until the job completes, total is 0 if unknown.
+func wait(j *job.Job, total int) int {
+	errCnt := 0
+	for {
+		select {
+		case err := <-j.Channel.ErrC:
+			errCnt++
+			log.Logger.Error(err)
+		case idx := <-j.Channel.BarC:
+			if total != 0 {
+				log.Logger.Infof("[progress] %d/%d", idx, total)
+			}
+		case ldText := <-j.Channel.LdC:
+			fmt.Printf("[load] %s\n", ldText)
+		case sample := <-j.Channel.SampleC:
+			fmt.Printf("[load] %s\n", sample)
+		case <-j.Channel.CompleteC:
+			if errCnt != 0 || j.Failed() != 0 {
+				log.Logger.Errorf("%d error(s), %d case(s) failed", errCnt, j.Failed())
+				return finish(exitFail)
+			}
+			return finish(exitPass)
+		}
+	}
+}
+
+func printLog() {
+	t, err := log.Track(logName)
+	if err != nil {
+		panic(err)
+	}
+	for l := range t.Lines {
+		fmt.Println(l.Text)
+	}
+}
+
+// finish leaves the tail goroutine a moment to flush the last lines.
+func finish(code int) int {
+	time.Sleep(500 * time.Millisecond)
+	return code
+}
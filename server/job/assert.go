@@ -0,0 +1,91 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"pictorial/log"
+	"pictorial/mysql"
+	"pictorial/widget"
+	"strings"
+)
+
+const diffSuffix = ".diff"
+
+// RecordPath is the dir the transcripts of the scripts are recorded into as golden outputs, instead of diffing them.
+var RecordPath string
+
+// settle writes the transcripts of a script case and decides its status,
+// a golden output takes precedence over the stderr of the mysql client.
+// A case without errors and without a golden output is unverified.
+func (j *Job) settle(c *CaseResult, e *widget.Example, outputs [][]string, errOuts []string) {
+	name := e.Value
+	var warn, fail, missing, recorded []string
+	for n, output := range outputs {
+		transcript := resultName(name, len(outputs), n+1)
+		j.writeResultFile(name, len(outputs), n+1, output)
+		if RecordPath != "" {
+			fName, err := e.Record(RecordPath, transcript, output)
+			switch {
+			case err != nil:
+				fail = append(fail, err.Error())
+			case errOuts[n] != "":
+				warn = append(warn, errOuts[n])
+			}
+			recorded = append(recorded, fName)
+			continue
+		}
+		verified, err := j.assert(e, transcript, output)
+		switch {
+		case err != nil:
+			fail = append(fail, err.Error())
+		case !verified && errOuts[n] != "":
+			warn = append(warn, errOuts[n])
+		case !verified:
+			missing = append(missing, transcript+widget.ExpectedSuffix)
+		}
+	}
+	switch {
+	case len(fail) != 0:
+		j.report.finish(c, StatusFail, strings.Join(fail, "; "))
+		log.Logger.Infof("[fail] %s: %s", name, strings.Join(fail, "; "))
+	case len(warn) != 0:
+		j.report.finish(c, StatusWarn, strings.Join(warn, "; "))
+		log.Logger.Infof("[warn] %s: %s", name, strings.Join(warn, "; "))
+	case len(missing) != 0:
+		msg := fmt.Sprintf("no golden output %s", strings.Join(missing, ", "))
+		j.report.finish(c, StatusUnverified, msg)
+		log.Logger.Infof("[unverified] %s: %s", name, msg)
+	case len(recorded) != 0:
+		msg := fmt.Sprintf("recorded %s", strings.Join(recorded, ", "))
+		j.report.finish(c, StatusPass, msg)
+		log.Logger.Infof("[record] %s: %s", name, msg)
+	default:
+		j.report.finish(c, StatusPass, "")
+		log.Logger.Infof("[pass] %s", name)
+	}
+}
+
+// assert diffs the transcript against its golden output, it returns false if there is none.
+func (j *Job) assert(e *widget.Example, transcript string, output []string) (bool, error) {
+	expected, err := e.Expected(transcript)
+	if err != nil {
+		return false, err
+	}
+	if expected == nil {
+		return false, nil
+	}
+	diff, err := mysql.Compare(expected, output)
+	if err != nil {
+		return true, fmt.Errorf("%s%s: %w", transcript, widget.ExpectedSuffix, err)
+	}
+	if len(diff) == 0 {
+		return true, nil
+	}
+	fName := filepath.Join(j.resultPath, transcript+diffSuffix)
+	if err := os.WriteFile(fName, []byte(strings.Join(diff, "\n")+"\n"), 0644); err != nil {
+		return true, err
+	}
+	j.report.attach(fName)
+	return true, fmt.Errorf("%s differs from %s%s, see %s", transcript, transcript, widget.ExpectedSuffix, fName)
+}
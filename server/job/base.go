@@ -1,6 +1,7 @@
 package job
 
 import (
+	"context"
 	"fmt"
 	ms "github.com/go-mysql-org/go-mysql/mysql"
 	"pictorial/bench"
@@ -44,16 +45,16 @@ func (j *Job) runDataSeparation() error {
 	output = append(output, out...)
 	log.Logger.Infof("[%s] create placement policy [%s,%s] for label %s [%s,%s]", ov, policyP1, policyP2, key, values[0], values[1])
 	log.Logger.Infof("[%s] create table %s.%s for %s, %s.%s for %s and init data...", ov, db, table1, policyP1, db, table2, policyP2)
-	sb := bench.Sysbench{
+	o := bench.Oltp{
 		Test:      bench.OltpReadWrite,
 		Mysql:     mysql.M,
 		Db:        "poc",
 		TableSize: 100000,
 		Tables:    2,
 		Threads:   5,
-		Cmd:       "prepare",
+		Cmd:       bench.CmdPrepare,
 	}
-	if _, err := sb.Run(); err != nil {
+	if err := o.Prepare(context.Background()); err != nil {
 		return err
 	}
 	alterSQL := mysql.AlterPlacementPolicy(fmt.Sprintf("%s.%s", db, table1), policyP1) +
@@ -0,0 +1,109 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/ssh"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogCollect is [logs], the logs of the components are collected into the result dir at the end of a job.
+type LogCollect struct {
+	Enable bool
+	// Check scans the logs by LogRules.
+	Check bool
+	// Padding is added before the start of the job, so the logs before the fault are collected as well.
+	Padding time.Duration
+	// Components are the roles whose logs are collected, tidb, pd, tikv or tiflash.
+	Components []string
+}
+
+var Logs = LogCollect{
+	Enable:     true,
+	Check:      true,
+	Padding:    time.Minute,
+	Components: []string{"tidb", "pd", "tikv", "tiflash"},
+}
+
+const logsDir = "logs"
+
+// faultHost marks the host of a fault, its dmesg and journal are collected with the logs.
+func (j *Job) faultHost(hosts ...string) {
+	for _, h := range hosts {
+		j.faulted[h] = true
+	}
+}
+
+// collectLogs slices the logs of the components to the job window on each host in parallel,
+// they are kept as logs/<host>.tar.gz in the result dir.
+func (j *Job) collectLogs() {
+	if !Logs.Enable {
+		return
+	}
+	from, to := j.begin.Add(-Logs.Padding), time.Now()
+	dirs := make(map[string]map[string]string)
+	var hosts []string
+	add := func(host string) {
+		if _, ok := dirs[host]; !ok {
+			dirs[host] = make(map[string]string)
+			hosts = append(hosts, host)
+		}
+	}
+	for _, role := range Logs.Components {
+		t, ok := cTypeByName[role]
+		if !ok {
+			log.Logger.Warnf("[logs] unknown component %s, skip", role)
+			continue
+		}
+		for _, c := range j.components[t] {
+			add(c.Host)
+			port := comp.CleanLeaderFlag(c.Port)
+			dirs[c.Host][fmt.Sprintf("%s-%s", role, port)] = logDir(role, c)
+		}
+	}
+	for h := range j.faulted {
+		add(h)
+	}
+	if len(hosts) == 0 {
+		return
+	}
+	out := filepath.Join(j.resultPath, logsDir)
+	if err := os.MkdirAll(out, os.ModePerm); err != nil {
+		log.Logger.Warnf("[logs] %s", err.Error())
+		return
+	}
+	log.Logger.Infof("[logs] collect the logs of %d host(s) since %s", len(hosts), from.Format(time.RFC3339))
+	results := ssh.S.Parallel(hosts, func(host string) ([]byte, error) {
+		bundle := filepath.Join(out, fmt.Sprintf("%s.tar.gz", host))
+		r, err := ssh.S.CollectLogs(host, dirs[host], from, to, j.faulted[host], bundle)
+		if err != nil {
+			return nil, err
+		}
+		j.report.attach(bundle)
+		return []byte(r.String()), nil
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			log.Logger.Warnf("[logs] %s failed: %s", r.Host, r.Err.Error())
+			continue
+		}
+		log.Logger.Infof("[logs] %s: %s", r.Host, string(r.Output))
+	}
+}
+
+// logDir is the log dir of the instance in the topology, or log under the deploy dir.
+func logDir(role string, c comp.Component) string {
+	if ssh.S.Topology != nil {
+		if port, err := strconv.Atoi(comp.CleanLeaderFlag(c.Port)); err == nil {
+			if i, ok := ssh.S.Topology.Find(role, c.Host, port); ok {
+				return i.LogDir
+			}
+		}
+	}
+	return filepath.Join(strings.TrimSuffix(c.DeployPath, "/bin"), "log")
+}
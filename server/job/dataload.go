@@ -1,6 +1,7 @@
 package job
 
 import (
+	"context"
 	"fmt"
 	"github.com/gizak/termui/v3/widgets"
 	"path/filepath"
@@ -18,13 +19,13 @@ func (j *Job) runLoadData() error {
 		e := widget.ChangeToExample(node)
 		switch e.OType {
 		case operator.LoadDataTPCC:
-			err = j.runTPCCLoadData()
+			err = j.runCase(node, j.runTPCCLoadData)
 		case operator.LoadDataImportInto:
-			err = j.runImportInto()
+			err = j.runCase(node, j.runImportInto)
 		case operator.LoadData:
-			err = j.runLoadDataJob()
+			err = j.runCase(node, j.runLoadDataJob)
 		case operator.LoadDataSelectIntoOutFile:
-			err = j.runSelectIntoOutFile()
+			err = j.runCase(node, j.runSelectIntoOutFile)
 		}
 		return true
 	})
@@ -33,28 +34,22 @@ func (j *Job) runLoadData() error {
 
 func (j *Job) runTPCCLoadData() error {
 	ov := operator.GetOTypeValue(operator.LoadDataTPCC)
-	clean := bench.Tpcc{
+	t := &bench.Tpcc{
 		Mysql:      mysql.M,
 		DB:         "poc",
 		Warehouses: 10,
 		Threads:    5,
-		Cmd:        "clean",
+		Cmd:        bench.CmdPrepare,
 	}
-	prepare := clean
-	prepare.Cmd = "prepare"
 	logPath := fmt.Sprintf("%s/%s.log", j.resultPath, ov)
-	log.Logger.Infof("[%s] %s", ov, prepare.String())
-	go Ld.captureLoadLog(logPath, j.Channel.ErrC, j.Channel.LdC)
-	originalCmd := Ld.Cmd
+	log.Logger.Infof("[%s] %s", ov, t.String())
 	defer func() {
-		Ld.Cmd = originalCmd
+		j.progress(1)
 	}()
-	Ld.Cmd = clean.String() + prepare.String()
-	Ld.run(logPath, j.Channel.ErrC, nil)
-	defer func() {
-		j.Channel.BarC <- 1
-	}()
-	return nil
+	if err := t.Cleanup(); err != nil {
+		return err
+	}
+	return Ld.run(logPath, j.Channel, &Workload{Name: ov, Runner: t})
 }
 
 func (j *Job) runImportInto() error {
@@ -80,7 +75,7 @@ func (j *Job) runImportInto() error {
 		return err
 	}
 	defer j.writeResultFile(ov, 1, 0, output)
-	j.Channel.BarC <- 1
+	j.progress(1)
 	return nil
 }
 
@@ -105,7 +100,7 @@ func (j *Job) runLoadDataJob() error {
 	}
 	defer func() {
 		j.writeResultFile(ov, 1, 0, output)
-		j.Channel.BarC <- 1
+		j.progress(1)
 	}()
 	return nil
 }
@@ -116,20 +111,17 @@ func (j *Job) runSelectIntoOutFile() error {
 	if !hit {
 		return fmt.Errorf("[%s] please connect the tidb-server on this server, If not, deploy one.", ov)
 	}
-	if err := bench.InstallSysBench(); err != nil {
-		return err
-	}
-	sb := bench.Sysbench{
+	o := bench.Oltp{
 		Test:      bench.OltpInsert,
 		Mysql:     mysql.M,
 		Db:        "poc",
 		TableSize: 1000000,
 		Tables:    1,
 		Threads:   5,
-		Cmd:       "prepare",
+		Cmd:       bench.CmdPrepare,
 	}
-	log.Logger.Infof("[%s] %s", ov, sb.String())
-	if _, err := sb.Run(); err != nil {
+	log.Logger.Infof("[%s] %s", ov, o.String())
+	if err := o.Prepare(context.Background()); err != nil {
 		return err
 	}
 	table := "poc.sbtest1"
@@ -142,7 +134,7 @@ func (j *Job) runSelectIntoOutFile() error {
 	}
 	defer func() {
 		j.writeResultFile(ov, 1, 0, output)
-		j.Channel.BarC <- 1
+		j.progress(1)
 	}()
 	return nil
 }
@@ -150,26 +142,17 @@ func (j *Job) runSelectIntoOutFile() error {
 func (j *Job) runDataDistribution() error {
 	ov := operator.GetOTypeValue(operator.DataDistribution)
 	lName := fmt.Sprintf("%s/%s.log", j.resultPath, ov)
-	sb := bench.Sysbench{
+	o := &bench.Oltp{
 		Test:      bench.OltpInsert,
 		Mysql:     mysql.M,
 		Db:        "poc",
 		TableSize: 10000000,
 		Tables:    1,
 		Threads:   5,
-		Cmd:       "prepare",
+		Cmd:       bench.CmdPrepare,
 	}
-	log.Logger.Infof("[%s] %s", ov, sb.String())
-	if err := bench.InstallSysBench(); err != nil {
-		return err
-	}
-	originalCmd := Ld.Cmd
-	defer func() {
-		Ld.Cmd = originalCmd
-	}()
-	Ld.Cmd = sb.String()
-	go Ld.captureLoadLog(lName, j.Channel.ErrC, j.Channel.LdC)
-	Ld.run(lName, j.Channel.ErrC, nil)
-	j.BarC <- 1
-	return nil
+	log.Logger.Infof("[%s] %s", ov, o.String())
+	err := Ld.run(lName, j.Channel, &Workload{Name: ov, Runner: o})
+	j.progress(1)
+	return err
 }
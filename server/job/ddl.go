@@ -1,15 +1,14 @@
 package job
 
 import (
+	"context"
 	"fmt"
 	"github.com/gizak/termui/v3/widgets"
-	"path/filepath"
 	"pictorial/bench"
 	"pictorial/log"
 	"pictorial/mysql"
 	"pictorial/operator"
 	"pictorial/widget"
-	"time"
 )
 
 func (j *Job) runOnlineDDL() error {
@@ -18,9 +17,11 @@ func (j *Job) runOnlineDDL() error {
 		e := widget.ChangeToExample(node)
 		switch e.OType {
 		case operator.OnlineDDLAddIndex, operator.OnlineDDLModifyColumn:
-			err = j.runOnlineDDLAlter(e.OType)
+			err = j.runCase(node, func() error {
+				return j.runOnlineDDLAlter(e.OType)
+			})
 		case operator.AddIndexPerformance:
-			err = j.runAddIndexPerformance()
+			err = j.runCase(node, j.runAddIndexPerformance)
 		}
 		return true
 	})
@@ -29,31 +30,24 @@ func (j *Job) runOnlineDDL() error {
 
 func (j *Job) runOnlineDDLAlter(oType operator.OType) error {
 	ov := operator.GetOTypeValue(oType)
-	if err := bench.InstallSysBench(); err != nil {
-		return err
-	}
-	sb := bench.Sysbench{
+	o := &bench.Oltp{
 		Test:      bench.OltpReadWrite,
 		Mysql:     mysql.M,
 		Db:        "poc",
 		TableSize: 1000000,
 		Tables:    1,
 		Threads:   5,
-		Cmd:       "prepare",
+		Cmd:       bench.CmdPrepare,
+	}
+	log.Logger.Info(fmt.Sprintf("[%s] init data: %s", ov, o.String()))
+	if err := o.Prepare(context.Background()); err != nil {
+		return err
 	}
-	log.Logger.Info(fmt.Sprintf("[%s] init data: %s", ov, sb.String()))
-	if _, err := sb.Run(); err != nil {
+	o.Cmd = bench.CmdRun
+	log.Logger.Infof("[%s] run %s.", ov, bench.GetSysbenchTpValue(bench.OltpReadWrite))
+	if err := j.startLoad(&Workload{Name: ov, Runner: o}); err != nil {
 		return err
 	}
-	go func() {
-		sb.Cmd = "run"
-		log.Logger.Infof("[%s] run sysbench %s.", ov, bench.GetSysbenchTpValue(bench.OltpReadWrite))
-		Ld.Cmd = sb.String()
-		logName := filepath.Join(j.resultPath, "load.log")
-		go Ld.captureLoadLog(logName, j.ErrC, j.LdC)
-		go Ld.run(logName, j.ErrC, j.StopC)
-	}()
-	time.Sleep(1 * time.Second)
 
 	table := "poc.sbtest1"
 	index := "k_2(c)"
@@ -82,20 +76,17 @@ func (j *Job) runAddIndexPerformance() error {
 	index := "k_2(c)"
 	addIndexSQL := mysql.AddIndex(table, index)
 	ov := operator.GetOTypeValue(operator.AddIndexPerformance)
-	if err := bench.InstallSysBench(); err != nil {
-		return err
-	}
-	sb := bench.Sysbench{
+	o := bench.Oltp{
 		Test:      bench.OltpReadWrite,
 		Mysql:     mysql.M,
 		Db:        "poc",
 		TableSize: 5000000,
 		Tables:    1,
 		Threads:   5,
-		Cmd:       "prepare",
+		Cmd:       bench.CmdPrepare,
 	}
-	log.Logger.Info(fmt.Sprintf("[%s] init data: %s", ov, sb.String()))
-	if _, err := sb.Run(); err != nil {
+	log.Logger.Info(fmt.Sprintf("[%s] init data: %s", ov, o.String()))
+	if err := o.Prepare(context.Background()); err != nil {
 		return err
 	}
 	cntDown(addIndexSQL, Ld.Interval)
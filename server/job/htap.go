@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pictorial/bench"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/mysql"
+	"pictorial/operator"
+	"time"
+)
+
+// HtapCase is the htap workload case, it is set from [htap].
+var HtapCase = bench.Htap{
+	DB:        "poc",
+	Replicas:  1,
+	Rows:      100000,
+	Threads:   5,
+	APThreads: 2,
+	Duration:  2 * time.Minute,
+}
+
+// runHtap writes to tikv and runs the mpp queries for the duration of the case, the freshness lag and the latency
+// of the queries are the summary of the workload, then tikv and tiflash are checked to return the same result.
+func (j *Job) runHtap() error {
+	ov := operator.GetOTypeValue(operator.HtapWorkload)
+	defer j.progress(1)
+	if len(j.components[comp.TiFlash]) == 0 {
+		return fmt.Errorf("[%s] there is no tiflash in the cluster", ov)
+	}
+	h := HtapCase
+	h.Mysql = mysql.M
+	h.Cmd = bench.CmdRun
+	if h.Duration == 0 {
+		h.Duration = 2 * time.Minute
+	}
+	logPath := filepath.Join(j.resultPath, fmt.Sprintf("%s.log", ov))
+	log.Logger.Infof("[%s] %s", ov, h.String())
+	if err := Ld.run(logPath, j.Channel, &Workload{Name: ov, Runner: &h}); err != nil {
+		return err
+	}
+	j.report.attach(logPath)
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return h.Check(context.Background(), f)
+}
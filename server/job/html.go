@@ -0,0 +1,237 @@
+package job
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pictorial/util/chart"
+	"strings"
+	"time"
+)
+
+const reportHTML = "report.html"
+
+// maxEmbedded is the size of a text artifact embedded in the html report, the rest is truncated.
+const maxEmbedded = 256 << 10
+
+//go:embed "resource/report.html"
+var reportTemplate string
+
+type htmlReport struct {
+	*Report
+	Suites     []htmlSuite
+	Throughput []template.URL
+	Timeline   []htmlBar
+	Others     []htmlArtifact
+}
+
+type htmlSuite struct {
+	Name       string
+	Pass       int
+	Fail       int
+	Unverified int
+	Cases      []htmlCase
+}
+
+type htmlCase struct {
+	*CaseResult
+	Artifacts []htmlArtifact
+}
+
+type htmlArtifact struct {
+	Name  string
+	Image template.URL
+	Lines []htmlLine
+	// Truncated is the size of the file if it is larger than maxEmbedded.
+	Truncated int64
+}
+
+type htmlLine struct {
+	Class string
+	Text  string
+}
+
+// htmlBar is a case on the fault timeline, the positions are percentages of the whole job.
+type htmlBar struct {
+	Name    string
+	Status  Status
+	Left    float64
+	Width   float64
+	Recover float64
+}
+
+// writeHTML renders the report, the transcripts and the images are embedded so the file can be handed over alone.
+func (r *Report) writeHTML(name string) error {
+	h := htmlReport{
+		Report: r,
+	}
+	idx := make(map[string]int)
+	for _, c := range r.Cases {
+		i, ok := idx[c.OType]
+		if !ok {
+			i = len(h.Suites)
+			idx[c.OType] = i
+			h.Suites = append(h.Suites, htmlSuite{Name: c.OType})
+		}
+		s := &h.Suites[i]
+		hc := htmlCase{CaseResult: c}
+		for _, a := range c.Artifacts {
+			hc.Artifacts = append(hc.Artifacts, embedArtifact(a))
+		}
+		switch c.Status {
+		case StatusPass:
+			s.Pass++
+		case StatusUnverified:
+			s.Unverified++
+		default:
+			s.Fail++
+		}
+		s.Cases = append(s.Cases, hc)
+	}
+	for _, a := range r.Artifacts {
+		h.Others = append(h.Others, embedArtifact(a))
+	}
+	h.Timeline = r.timeline()
+	h.Throughput = r.throughputCharts()
+
+	t, err := template.New(reportHTML).Funcs(template.FuncMap{
+		"seconds": seconds,
+		"time": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return t.Execute(f, h)
+}
+
+// timeline places the cases with a target, which are the faults, on the duration of the job.
+func (r *Report) timeline() []htmlBar {
+	total := r.End.Sub(r.Start).Seconds()
+	if total <= 0 {
+		return nil
+	}
+	var bars []htmlBar
+	for _, c := range r.Cases {
+		if c.Target == "" {
+			continue
+		}
+		b := htmlBar{
+			Name:   fmt.Sprintf("%s %s", c.OType, c.Target),
+			Status: c.Status,
+			Left:   c.Start.Sub(r.Start).Seconds() / total * 100,
+			Width:  c.Duration / total * 100,
+		}
+		if c.TimeToRecover != 0 && c.Duration != 0 {
+			b.Recover = c.TimeToRecover / c.Duration * 100
+		}
+		bars = append(bars, b)
+	}
+	return bars
+}
+
+func embedArtifact(path string) htmlArtifact {
+	a := htmlArtifact{
+		Name: filepath.Base(path),
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		a.Lines = []htmlLine{{Class: "del", Text: err.Error()}}
+		return a
+	}
+	if fi.IsDir() {
+		return a
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		a.Lines = []htmlLine{{Class: "del", Text: err.Error()}}
+		return a
+	}
+	contentType := http.DetectContentType(b)
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		a.Image = template.URL(fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(b)))
+		return a
+	case !strings.HasPrefix(contentType, "text/"):
+		return a
+	}
+	if len(b) > maxEmbedded {
+		b = b[:maxEmbedded]
+		a.Truncated = fi.Size()
+	}
+	isDiff := strings.HasSuffix(path, diffSuffix)
+	for _, l := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		hl := htmlLine{Text: l}
+		switch {
+		case isDiff && strings.HasPrefix(l, "@@"):
+			hl.Class = "hunk"
+		case isDiff && strings.HasPrefix(l, "-"):
+			hl.Class = "del"
+		case isDiff && strings.HasPrefix(l, "+"):
+			hl.Class = "add"
+		case strings.HasPrefix(l, "ERROR"):
+			hl.Class = "del"
+		}
+		a.Lines = append(a.Lines, hl)
+	}
+	return a
+}
+
+// throughputCharts draws the tps, qps and the latency of the load samples, a line for each workload.
+func (r *Report) throughputCharts() []template.URL {
+	if len(r.samples) == 0 {
+		return nil
+	}
+	var throughput, latency []chart.Series
+	for _, g := range groupSamples(r.samples) {
+		prefix := ""
+		if g[0].Name != "" {
+			prefix = g[0].Name + " "
+		}
+		tps := chart.Series{Name: prefix + "tps"}
+		qps := chart.Series{Name: prefix + "qps"}
+		errs := chart.Series{Name: prefix + "err/s"}
+		lat := chart.Series{Name: fmt.Sprintf("%sp%s (ms)", prefix, g[0].Percentile)}
+		for _, s := range g {
+			for _, p := range []struct {
+				series *chart.Series
+				value  float64
+			}{{&tps, s.TPS}, {&qps, s.QPS}, {&errs, s.Errors}, {&lat, s.Latency}} {
+				p.series.Times = append(p.series.Times, s.Time)
+				p.series.Values = append(p.series.Values, p.value)
+			}
+		}
+		throughput = append(throughput, tps)
+		if average(qps.Values) != 0 {
+			throughput = append(throughput, qps)
+		}
+		if average(errs.Values) != 0 {
+			throughput = append(throughput, errs)
+		}
+		latency = append(latency, lat)
+	}
+	var imgs []template.URL
+	for _, c := range []struct {
+		title  string
+		series []chart.Series
+	}{{"throughput", throughput}, {"latency", latency}} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, chart.Line(c.title, c.series)); err != nil {
+			continue
+		}
+		imgs = append(imgs, template.URL("data:image/png;base64,"+base64.StdEncoding.EncodeToString(buf.Bytes())))
+	}
+	return imgs
+}
@@ -17,7 +17,6 @@ import (
 	"pictorial/widget"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -27,24 +26,40 @@ type Job struct {
 	components map[comp.CType][]comp.Component
 	Channel
 	resultPath string
+	report     *Report
+	probe      *prober
+	// done is the count of the nodes of the finished phases.
+	done int
+	// noRecover skips the recovery after the fault, it is set by the scenario step.
+	noRecover bool
+	// startAt is the start of the current phase or scenario, the metrics are rendered since it.
+	startAt time.Time
+	// begin is the start of the job, the logs are collected since it.
+	begin time.Time
+	// faulted are the hosts of the faults, their dmesg and journal are collected.
+	faulted map[string]bool
 }
 
 type Channel struct {
 	BarC      chan int
 	LdC       chan string
-	StopC     chan bool
+	SampleC   chan bench.Sample
 	ErrC      chan error
 	CompleteC chan bool
 }
 
 var loadJob = []operator.OType{
 	operator.ScaleIn,
+	operator.ScaleOut,
 	operator.Kill,
 	operator.DataCorrupted,
 	operator.Crash,
 	operator.Disaster,
 	operator.Reboot,
 	operator.DiskFull,
+	operator.NetworkIsolation,
+	operator.NetworkDelay,
+	operator.NetworkPartition,
 }
 
 func isLoadJob(o operator.OType) bool {
@@ -58,15 +73,20 @@ func isLoadJob(o operator.OType) bool {
 
 var renderJob = []operator.OType{
 	operator.ScaleIn,
+	operator.ScaleOut,
 	operator.Kill,
 	operator.DataCorrupted,
 	operator.Crash,
 	operator.Disaster,
 	operator.Reboot,
 	operator.DiskFull,
+	operator.NetworkIsolation,
+	operator.NetworkDelay,
+	operator.NetworkPartition,
 	operator.DataDistribution,
 	operator.OnlineDDLAddIndex,
 	operator.OnlineDDLModifyColumn,
+	operator.HtapWorkload,
 }
 
 func isRenderJob(o operator.OType) bool {
@@ -91,7 +111,7 @@ func New(e map[string][]string, s *widgets.Tree) Job {
 		}
 		return filepath.Join(fp, resultPath)
 	}
-	Ld.IsOver = false
+	Ld.reset()
 	c, err := comp.New()
 	if err != nil {
 		panic(err)
@@ -103,11 +123,13 @@ func New(e map[string][]string, s *widgets.Tree) Job {
 		Channel: Channel{
 			BarC:      make(chan int),
 			LdC:       make(chan string),
-			StopC:     make(chan bool),
+			SampleC:   make(chan bench.Sample),
 			ErrC:      make(chan error),
 			CompleteC: make(chan bool),
 		},
 		resultPath: mkdirResultPath(),
+		report:     newReport(),
+		faulted:    make(map[string]bool),
 	}
 }
 
@@ -115,13 +137,14 @@ const CompleteSignal = "complete_signal"
 
 func (j *Job) Run() {
 
-	oType := j.tp()
-	ov := operator.GetOTypeValue(oType)
-	j.printSelected(oType)
-	if err := resetDB(); err != nil {
-		j.ErrC <- err
-		return
+	phases := j.phases()
+	scriptOnly := true
+	for _, p := range phases {
+		scriptOnly = scriptOnly && isScriptJob(p.oType)
 	}
+	j.report.Name = j.selected.Title
+	j.begin = time.Now()
+	j.printSelected()
 
 	// for job internal load, e.g disk_full
 	ctx, cancel := context.WithCancel(context.Background())
@@ -131,73 +154,251 @@ func (j *Job) Run() {
 
 	defer func() {
 		cancel()
+		waitAftercare()
 		shellCancel()
-		time.Sleep(1 * time.Second)
-		j.Channel.CompleteC <- true
-		log.Logger.Infof("complete, result at %s.", j.resultPath)
+		ssh.S.Close()
+		j.complete(!scriptOnly)
 	}()
 
-	switch oType {
-	case operator.Script, operator.OtherScript:
-		j.runScript()
-	case operator.SafetyScript:
-		j.runSafety()
-	default:
+	if err := resetDB(); err != nil {
+		j.ErrC <- err
+		return
+	}
+
+	if !scriptOnly {
 		if err := j.createOTypeResult(); err != nil {
 			j.ErrC <- err
 			return
 		}
-		if isLoadJob(oType) {
-			if Ld.Cmd != "" {
-				ldName := filepath.Join(j.resultPath, "load.log")
-				go Ld.run(ldName, j.Channel.ErrC, j.Channel.StopC)
-				go Ld.captureLoadLog(ldName, j.ErrC, j.LdC)
-				time.Sleep(time.Second * 1)
-				cntDown("start executing the test case", Ld.Interval)
-			}
-		}
-		var err error
-		switch oType {
-		case operator.DataSeparation:
-			err = j.runDataSeparation()
-		case operator.FlashBackCluster:
-			err = j.runFlashbackCluster()
-		case operator.GeneralLog:
-			err = j.runGeneralLogJob()
-		case operator.Disaster:
-			j.runLabel()
-		case operator.LoadDataTPCC, operator.LoadDataImportInto, operator.LoadData, operator.LoadDataSelectIntoOutFile:
-			err = j.runLoadData()
-		case operator.DataDistribution:
-			err = j.runDataDistribution()
-		case operator.OnlineDDLAddIndex, operator.AddIndexPerformance, operator.OnlineDDLModifyColumn:
-			err = j.runOnlineDDL()
-		case operator.InstallSysBench:
-			err = bench.InstallSysBench()
-		default:
-			j.runComponent(ctx)
+	}
+	for _, p := range phases {
+		j.selected = p.selected
+		if len(phases) > 1 {
+			log.Logger.Infof("[phase] %s, %d case(s)", operator.GetOTypeValue(p.oType), widget.TreeLength(p.selected))
 		}
-		if err != nil {
+		if err := j.runPhase(ctx, p.oType, len(phases) > 1); err != nil {
 			j.ErrC <- err
 		}
+		j.done += widget.TreeLength(p.selected)
+	}
+	if !scriptOnly {
+		j.checkLogs()
+		j.collectLogs()
 	}
 
 	if err := ssh.S.AfterCareShellLog(j.resultPath); err != nil {
 		j.ErrC <- err
 		return
 	}
-	if isRenderJob(oType) {
-		cntDown("grafana image render", Ld.Interval)
-		log.Logger.Debug(fmt.Sprintf("load over status: %v, cmd: %s", Ld.IsOver, Ld.Cmd))
-		if !Ld.IsOver && Ld.Cmd != "" {
-			j.Channel.StopC <- true
+}
+
+type phase struct {
+	oType    operator.OType
+	selected *widgets.Tree
+}
+
+// phases groups the selected nodes by OType in the order of their first appearance.
+func (j *Job) phases() []phase {
+	var ps []phase
+	nodes := make(map[operator.OType][]*widgets.TreeNode)
+	j.selected.Walk(func(node *widgets.TreeNode) bool {
+		e := widget.ChangeToExample(node)
+		if _, ok := nodes[e.OType]; !ok {
+			ps = append(ps, phase{oType: e.OType})
 		}
-		time.Sleep(1 * time.Second)
-		if err := j.components[comp.Grafana][0].Render(j.resultPath, ov); err != nil {
-			j.ErrC <- err
-			return
+		nodes[e.OType] = append(nodes[e.OType], node)
+		return true
+	})
+	for i := range ps {
+		t := widgets.NewTree()
+		t.Title = operator.GetOTypeValue(ps[i].oType)
+		t.SetNodes(nodes[ps[i].oType])
+		ps[i].selected = t
+	}
+	return ps
+}
+
+// runPhase runs the nodes of one OType, the panels are rendered into a sub dir if the job has more than one phase.
+func (j *Job) runPhase(ctx context.Context, oType operator.OType, shared bool) error {
+	ov := operator.GetOTypeValue(oType)
+	j.startAt = time.Now()
+	if isLoadJob(oType) {
+		if err := j.startLoad(); err != nil {
+			return err
+		}
+		if len(Ld.Workloads) != 0 {
+			cntDown("start executing the test case", Ld.Interval)
+		}
+	}
+	if err := j.execute(ctx, oType); err != nil {
+		j.ErrC <- err
+	}
+	if !isRenderJob(oType) {
+		return nil
+	}
+	waitScrape()
+	j.stopLoad()
+	if !shared {
+		return j.render(j.panelSet(ov))
+	}
+	to := filepath.Join(j.resultPath, ov)
+	if err := os.MkdirAll(to, os.ModePerm); err != nil {
+		return err
+	}
+	err := j.renderTo(to, j.panelSet(ov))
+	entries, _ := os.ReadDir(to)
+	for _, e := range entries {
+		j.report.attach(filepath.Join(to, e.Name()))
+	}
+	return err
+}
+
+// waitScrape waits for one step of the metrics, so the samples of the last case are in the panels rendered.
+func waitScrape() {
+	log.Logger.Infof("[metrics render] after %s...", comp.Metrics.Step)
+	time.Sleep(comp.Metrics.Step)
+}
+
+// progress reports the count of the current phase, the nodes of the finished phases are added.
+func (j *Job) progress(cnt int) {
+	j.Channel.BarC <- j.done + cnt
+}
+
+// complete stops the probe, writes the report and notifies the caller.
+func (j *Job) complete(collect bool) {
+	time.Sleep(1 * time.Second)
+	if j.probe != nil {
+		for _, a := range j.probe.stop(j.resultPath) {
+			j.report.attach(a)
+		}
+	}
+	j.report.workloads(Ld.results())
+	if samples := Ld.Samples(); len(samples) != 0 {
+		j.report.throughput(samples)
+		name := filepath.Join(j.resultPath, loadSamples)
+		if err := writeSamples(name, samples); err != nil {
+			log.Logger.Errorf("write %s failed: %s", name, err.Error())
+		} else {
+			j.report.attach(name)
+		}
+	}
+	if collect {
+		j.report.collect(j.resultPath)
+	}
+	if err := j.report.write(j.resultPath); err != nil {
+		log.Logger.Errorf("write report failed: %s", err.Error())
+	}
+	j.Channel.CompleteC <- true
+	log.Logger.Infof("complete, result at %s.", j.resultPath)
+}
+
+// startLoad starts the probe and the workloads in the background, all of the configured ones if none is given.
+func (j *Job) startLoad(ws ...*Workload) error {
+	if Pb.Enable && j.probe == nil {
+		p, err := startProbe()
+		if err != nil {
+			return err
 		}
+		j.probe = p
+	}
+	if len(ws) == 0 {
+		ws = Ld.Workloads
+	}
+	if len(ws) == 0 {
+		return nil
+	}
+	if err := Ld.start(j.resultPath, j.Channel, ws...); err != nil {
+		return err
+	}
+	time.Sleep(time.Second * 1)
+	return nil
+}
+
+// stopLoad stops the running workloads, or only the ones given.
+func (j *Job) stopLoad(ws ...*Workload) {
+	log.Logger.Debug(fmt.Sprintf("load running: %v", Ld.running()))
+	Ld.stop(ws...)
+	time.Sleep(1 * time.Second)
+}
+
+func (j *Job) render(set string) error {
+	return j.renderTo(j.resultPath, set)
+}
+
+// renderTo queries the panels from prometheus, grafana image renderer is used if it is configured or there is no prometheus.
+func (j *Job) renderTo(to, set string) error {
+	if comp.Metrics.Source == comp.SourcePrometheus && len(j.components[comp.Prometheus]) != 0 {
+		return j.components[comp.Prometheus][0].Capture(to, set, j.startAt)
+	}
+	if len(j.components[comp.Grafana]) == 0 {
+		return fmt.Errorf("neither prometheus nor grafana is found, skip render")
+	}
+	return j.components[comp.Grafana][0].Render(to, set, j.startAt)
+}
+
+// panelSet is the catalog index if the phase is one case declared in the panel sets, otherwise the operator.
+func (j *Job) panelSet(ov string) string {
+	if widget.TreeLength(j.selected) != 1 {
+		return ov
+	}
+	idx := widget.GetIdxByValue(widget.ChangeToExample(j.selected.SelectedNode()).Value)
+	if comp.HasPanelSet(idx) {
+		return idx
+	}
+	return ov
+}
+
+// execute runs the selected nodes of the OType.
+func (j *Job) execute(ctx context.Context, oType operator.OType) error {
+	var err error
+	switch oType {
+	case operator.Script, operator.OtherScript:
+		j.runScript()
+	case operator.SafetyScript:
+		j.runSafety()
+	case operator.DataSeparation:
+		err = j.runCase(j.selected.SelectedNode(), j.runDataSeparation)
+	case operator.FlashBackCluster:
+		err = j.runCase(j.selected.SelectedNode(), j.runFlashbackCluster)
+	case operator.GeneralLog:
+		err = j.runCase(j.selected.SelectedNode(), j.runGeneralLogJob)
+	case operator.Disaster:
+		j.runLabel(ctx)
+	case operator.NetworkPartition:
+		j.runPartition(ctx)
+	case operator.ScaleOut:
+		j.runScaleOut(ctx)
+	case operator.LoadDataTPCC, operator.LoadDataImportInto, operator.LoadData, operator.LoadDataSelectIntoOutFile:
+		err = j.runLoadData()
+	case operator.DataDistribution:
+		err = j.runCase(j.selected.SelectedNode(), j.runDataDistribution)
+	case operator.OnlineDDLAddIndex, operator.AddIndexPerformance, operator.OnlineDDLModifyColumn:
+		err = j.runOnlineDDL()
+	case operator.InstallSysBench:
+		err = j.runCase(j.selected.SelectedNode(), bench.InstallSysBench)
+	case operator.HtapWorkload:
+		err = j.runCase(j.selected.SelectedNode(), j.runHtap)
+	default:
+		j.runComponent(ctx)
 	}
+	return err
+}
+
+func (j *Job) Failed() int {
+	return j.report.failed()
+}
+
+func isScriptJob(o operator.OType) bool {
+	return o == operator.Script || o == operator.OtherScript || o == operator.SafetyScript
+}
+
+// runCase records a job level function as one case of the report.
+func (j *Job) runCase(node *widgets.TreeNode, fn func() error) error {
+	e := widget.ChangeToExample(node)
+	c := j.report.begin(e.Value, e.OType, e.CType, "")
+	err := fn()
+	j.report.finishErr(c, err)
+	return err
 }
 
 func IsCompleteSignal(err error) bool {
@@ -209,30 +410,26 @@ func (j *Job) runScript() {
 	var cnt int
 	j.selected.Walk(func(i *widgets.TreeNode) bool {
 		cnt++
-		var idx int32
 		var wg sync.WaitGroup
-		var errOut string
-		name := i.Value.String()
+		e := widget.ChangeToExample(i)
+		c := j.report.begin(e.Value, e.OType, comp.NoBody, "")
 		scripts := j.examples[i.Value.String()]
-		for _, s := range scripts {
+		outputs := make([][]string, len(scripts))
+		errOuts := make([]string, len(scripts))
+		for n, s := range scripts {
 			wg.Add(1)
-			go func(sql string) {
+			go func(n int, sql string) {
 				defer wg.Done()
-				n := atomic.AddInt32(&idx, 1)
 				output, err := mysql.M.ExecuteForceWithOutput(sql, mysql.M.User, mysql.M.Password)
 				if err != nil {
-					errOut = err.Error()
+					errOuts[n] = err.Error()
 				}
-				j.writeResultFile(name, len(scripts), int(n), output)
-			}(s)
+				outputs[n] = output
+			}(n, s)
 		}
 		wg.Wait()
-		if errOut != "" {
-			log.Logger.Infof("[warn] %s: %s", name, errOut)
-		} else {
-			log.Logger.Infof("[pass] %s", name)
-		}
-		j.Channel.BarC <- cnt
+		j.settle(c, e, outputs, errOuts)
+		j.progress(cnt)
 		return true
 	})
 }
@@ -241,13 +438,28 @@ func (j *Job) runComponent(ctx context.Context) {
 	var cnt int
 	j.selected.Walk(func(node *widgets.TreeNode) bool {
 		cnt++
+		// every case moves the bar, including the ones which fail.
+		defer j.progress(cnt)
 		e := widget.ChangeToExample(node)
 		ov := operator.GetOTypeValue(e.OType)
 		addr := strings.Trim(e.String(), comp.Leader)
 		var failMsg = "[%s] %s failed: %s"
+		if comp.IsSymbol(e.Value) {
+			var err error
+			if addr, err = comp.Resolve(e.Value); err != nil {
+				rc := j.report.begin(e.Value, e.OType, e.CType, e.Value)
+				j.report.finishErr(rc, err)
+				log.Logger.Errorf(failMsg, ov, e.Value, err.Error())
+				return true
+			}
+			log.Logger.Infof("[%s] %s is %s", ov, e.Value, addr)
+		}
+		rc := j.report.begin(e.Value, e.OType, e.CType, addr)
+		matched := false
 		for _, c := range j.components[e.CType] {
 			c.Port = comp.CleanLeaderFlag(c.Port)
 			if addr == net.JoinHostPort(c.Host, c.Port) {
+				matched = true
 				b := operator.Builder{
 					OType:      e.OType,
 					CType:      e.CType,
@@ -255,28 +467,55 @@ func (j *Job) runComponent(ctx context.Context) {
 					Port:       c.Port,
 					DeployPath: c.DeployPath,
 					Ctx:        ctx,
+					Peers:      j.peers(e.OType, c.Host),
 				}
 				r, err := b.Build()
 				if err != nil {
+					j.report.finishErr(rc, err)
 					log.Logger.Error(err)
 					return true
 				}
+				j.faultHost(c.Host)
+				faultAt := time.Now()
 				if err = r.Execute(); err != nil {
+					j.report.finishErr(rc, err)
 					log.Logger.Errorf(failMsg, ov, addr, err.Error())
+					restore(r)
 					return true
 				}
+				if isRecoverJob(e.OType) && !j.noRecover {
+					if err = j.recover(ctx, rc, faultAt, r); err != nil {
+						j.report.finishErr(rc, err)
+						log.Logger.Errorf(failMsg, ov, addr, err.Error())
+						return true
+					}
+				}
 			}
 		}
+		if !matched {
+			j.report.finish(rc, StatusFail, fmt.Sprintf("%s is not in the cluster", addr))
+		} else {
+			j.report.finish(rc, StatusPass, "")
+		}
 		time.Sleep(time.Second * Ld.Sleep)
-		j.Channel.BarC <- cnt
 		return true
 	})
 }
 
-func (j *Job) runLabel() {
+// runLabel crashes the tikvs of the label at the same time like an outage of the zone, the tikvs of a host are
+// crashed one by one and the hosts in parallel.
+func (j *Job) runLabel(ctx context.Context) {
 	kvs := j.components[comp.TiKV]
+	var cnt int
 	j.selected.Walk(func(i *widgets.TreeNode) bool {
+		cnt++
+		defer j.progress(cnt)
 		targetLabel := i.Value.String()
+		rc := j.report.begin(targetLabel, operator.Disaster, comp.TiKV, targetLabel)
+		var hosts []string
+		var crashed []operator.Operator
+		var failed []string
+		byHost := make(map[string][]operator.Operator)
 		for _, kv := range kvs {
 			for _, v := range kv.Labels {
 				if targetLabel == v {
@@ -286,33 +525,87 @@ func (j *Job) runLabel() {
 						OType: operator.Crash,
 						CType: comp.TiKV,
 					}
-					r, _ := b.Build()
-					if err := r.Execute(); err != nil {
-						log.Logger.Errorf("[disaster] %s failed: %v", net.JoinHostPort(b.Host, b.Port), err)
+					r, err := b.Build()
+					if err != nil {
+						failed = append(failed, fmt.Sprintf("%s: %v", net.JoinHostPort(kv.Host, kv.Port), err))
+						break
+					}
+					crashed = append(crashed, r)
+					if _, ok := byHost[kv.Host]; !ok {
+						hosts = append(hosts, kv.Host)
 					}
+					byHost[kv.Host] = append(byHost[kv.Host], r)
 					break
 				}
 			}
 		}
-		log.Logger.Infof("[disaster] %s", targetLabel)
-		j.Channel.BarC <- 1
+		if len(failed) != 0 {
+			j.report.finish(rc, StatusFail, strings.Join(failed, "; "))
+			log.Logger.Errorf("[disaster] %s failed: %s", targetLabel, strings.Join(failed, "; "))
+			return true
+		}
+		if len(crashed) == 0 {
+			j.report.finish(rc, StatusFail, fmt.Sprintf("label %s is not in the cluster", targetLabel))
+			log.Logger.Errorf("[disaster] label %s is not in the cluster", targetLabel)
+			return true
+		}
+		j.faultHost(hosts...)
+		faultAt := time.Now()
+		results := ssh.S.Parallel(hosts, func(host string) ([]byte, error) {
+			var errs []string
+			for _, r := range byHost[host] {
+				if err := r.Execute(); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+			if len(errs) != 0 {
+				return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+			}
+			return nil, nil
+		})
+		if err := ssh.Failed(results); err != nil {
+			failed = append(failed, err.Error())
+			log.Logger.Errorf("[disaster] %s failed: %v", targetLabel, err)
+		}
+		log.Logger.Infof("[disaster] %s, %d tikv(s) on %d host(s)", targetLabel, len(crashed), len(hosts))
+		// the tikvs which did crash are restarted even if the others failed.
+		switch {
+		case !j.noRecover:
+			if err := j.recover(ctx, rc, faultAt, crashed...); err != nil {
+				failed = append(failed, err.Error())
+				log.Logger.Errorf("[disaster] %s failed: %v", targetLabel, err)
+			}
+		case len(failed) != 0:
+			for _, r := range crashed {
+				restore(r)
+			}
+		}
+		if len(failed) != 0 {
+			j.report.finish(rc, StatusFail, strings.Join(failed, "; "))
+		} else {
+			j.report.finish(rc, StatusPass, "")
+		}
 		return true
 	})
-	cntDown("grafana image render", Ld.Interval)
+	waitScrape()
 }
 
-func (j *Job) writeResultFile(name string, len, n int, output []string) {
-	var fName string
+func resultName(name string, len, n int) string {
 	if len == 1 {
-		fName = filepath.Join(j.resultPath, name)
-	} else {
-		fName = filepath.Join(j.resultPath, fmt.Sprintf("%s_%d", name, n))
+		return name
 	}
+	return fmt.Sprintf("%s_%d", name, n)
+}
+
+func (j *Job) writeResultFile(name string, len, n int, output []string) {
+	fName := filepath.Join(j.resultPath, resultName(name, len, n))
 	f, err := os.Create(fName)
 	if err != nil {
 		log.Logger.Warnf("write %s failed: %s", name, err.Error())
+		return
 	}
 	defer f.Close()
+	j.report.attach(fName)
 	for _, o := range output {
 		_, err = io.WriteString(f, fmt.Sprintf("%s\n", o))
 		if err != nil {
@@ -322,7 +615,11 @@ func (j *Job) writeResultFile(name string, len, n int, output []string) {
 }
 
 func (j *Job) createOTypeResult() error {
-	result := fmt.Sprintf("%s/%s_%s", resultPath, j.selected.Title, log.DateFormat())
+	return j.createResult(j.selected.Title)
+}
+
+func (j *Job) createResult(title string) error {
+	result := fmt.Sprintf("%s/%s_%s", resultPath, title, log.DateFormat())
 	if err := os.MkdirAll(result, os.ModePerm); err != nil {
 		return err
 	}
@@ -334,10 +631,6 @@ func (j *Job) createOTypeResult() error {
 	return nil
 }
 
-func (j *Job) tp() operator.OType {
-	return j.selected.SelectedNode().Value.(*widget.Example).OType
-}
-
 func resetDB() error {
 	if _, err := mysql.M.ExecuteSQL("DROP DATABASE IF EXISTS poc"); err != nil {
 		return err
@@ -349,17 +642,17 @@ func resetDB() error {
 	return nil
 }
 
-func (j *Job) printSelected(oType operator.OType) {
+func (j *Job) printSelected() {
 	log.Logger.Info("you selected:")
-	ov := operator.GetOTypeValue(oType)
 	cnt := 0
 	j.selected.Walk(func(node *widgets.TreeNode) bool {
 		cnt++
-		switch oType {
+		e := widget.ChangeToExample(node)
+		switch e.OType {
 		case operator.Script, operator.OtherScript, operator.SafetyScript:
 			log.Logger.Infof("[%d] %s", cnt, node.Value.String())
 		default:
-			log.Logger.Infof("[%d] %s_%s", cnt, ov, node.Value.String())
+			log.Logger.Infof("[%d] %s_%s", cnt, operator.GetOTypeValue(e.OType), node.Value.String())
 		}
 		return true
 	})
@@ -2,49 +2,466 @@ package job
 
 import (
 	"context"
+	"encoding/csv"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"pictorial/bench"
 	"pictorial/log"
 	"pictorial/ssh"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Restart policies of a workload whose command exits by itself.
+const (
+	RestartNo        = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// restartDelay is the wait before a workload is restarted.
+const restartDelay = 5 * time.Second
+
+type WorkloadStatus string
+
+const (
+	WorkloadRunning WorkloadStatus = "running"
+	// WorkloadExited is a command exiting by itself without error, WorkloadFailed with an error.
+	WorkloadExited  WorkloadStatus = "exited"
+	WorkloadFailed  WorkloadStatus = "failed"
+	WorkloadStopped WorkloadStatus = "stopped"
+)
+
+// Workload is a named background command of [[load]], the unnamed one is the [load] table.
+// Runner runs in process instead of the Cmd if it is set, and Check verifies its data after each recovery.
+type Workload struct {
+	Name    string
+	Cmd     string
+	Runner  bench.Runner
+	Check   bool
+	Restart string
+
+	mu       sync.Mutex
+	logPath  string
+	status   WorkloadStatus
+	restarts int
+	err      error
+	summary  *bench.Summary
+	stopC    chan struct{}
+	doneC    chan struct{}
+}
+
+// WorkloadResult is the status of a workload at the end of the job.
+type WorkloadResult struct {
+	Name     string         `json:"name"`
+	Cmd      string         `json:"cmd"`
+	Status   WorkloadStatus `json:"status"`
+	Restarts int            `json:"restarts,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	// Summary is the transactions, errors and latencies of the last run of a built-in workload.
+	Summary *bench.Summary `json:"summary,omitempty"`
+}
+
+// Load runs the workloads in the background, each of them has its own log, stop channel and restart policy.
 type Load struct {
-	Cmd      string
-	Interval int64
-	Sleep    time.Duration
-	IsOver   bool
+	Workloads []*Workload
+	Interval  int64
+	Sleep     time.Duration
+
+	mu sync.Mutex
+	// started are the workloads started by the job, including the ones of the cases, e.g. online ddl.
+	started []*Workload
+	// samples are parsed from the report lines of the load logs of the job.
+	samples []bench.Sample
 }
 
 var Ld Load
 
-func (l *Load) run(lgName string, errC chan error, stopLdC chan bool) {
-	log.Logger.Infof("start load: %s", l.Cmd)
-	action := "load ends and exits normally"
-	args := []string{"-c", l.Cmd}
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		<-stopLdC
-		action = fmt.Sprintf("receive kill signal, cancel normally: %s.", l.Cmd)
+const (
+	loadLog     = "load.log"
+	loadSamples = "load.csv"
+)
+
+func (w *Workload) title() string {
+	if w.Name == "" {
+		return "load"
+	}
+	return w.Name
+}
+
+// logName is load.log for the unnamed workload and load_<name>.log for the others.
+func (w *Workload) logName() string {
+	if w.Name == "" {
+		return loadLog
+	}
+	return fmt.Sprintf("load_%s.log", w.Name)
+}
+
+func (w *Workload) command() string {
+	if w.Runner != nil {
+		return w.Runner.String()
+	}
+	return w.Cmd
+}
+
+func (w *Workload) setStatus(status WorkloadStatus, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = status
+	w.err = err
+}
+
+func (w *Workload) isRunning() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status == WorkloadRunning
+}
+
+func (w *Workload) result() WorkloadResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r := WorkloadResult{
+		Name:     w.title(),
+		Cmd:      w.command(),
+		Status:   w.status,
+		Restarts: w.restarts,
+		Summary:  w.summary,
+	}
+	if w.err != nil {
+		r.Error = w.err.Error()
+	}
+	return r
+}
+
+// start runs the command until it is stopped or exits without a restart, the log is appended to,
+// so the one of the previous load phase in the same result dir is kept.
+func (w *Workload) start(name string, c Channel, errC chan error) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.logPath = name
+	w.status = WorkloadRunning
+	w.restarts = 0
+	w.err = nil
+	w.summary = nil
+	w.stopC = make(chan struct{})
+	w.doneC = make(chan struct{})
+	w.mu.Unlock()
+	go w.captureLoadLog(name, offset, c)
+	go w.run(name, c, errC)
+	return nil
+}
+
+func (w *Workload) run(name string, c Channel, errC chan error) {
+	defer close(w.doneC)
+	for {
+		log.Logger.Infof("[%s] start load: %s", w.title(), w.command())
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-w.stopC:
+			case <-ctx.Done():
+			}
+			cancel()
+		}()
+		var err error
+		if w.Runner != nil {
+			err = w.runRunner(ctx, name, c)
+		} else {
+			_, err = ssh.S.RunLocalWithContext(ctx, "sh", []string{"-c", w.Cmd}, name)
+		}
 		cancel()
-	}()
-	if _, err := ssh.S.RunLocalWithContext(ctx, "sh", args, lgName); err != nil {
-		errC <- err
+		select {
+		case <-w.stopC:
+			log.Logger.Infof("[%s] receive kill signal, cancel normally.", w.title())
+			w.setStatus(WorkloadStopped, nil)
+			return
+		default:
+		}
+		restart := w.Restart == RestartAlways || (w.Restart == RestartOnFailure && err != nil)
+		if err != nil {
+			log.Logger.Errorf("[%s] load failed: %s", w.title(), err.Error())
+			w.setStatus(WorkloadFailed, err)
+			if !restart && errC != nil {
+				errC <- err
+			}
+		} else {
+			log.Logger.Infof("[%s] load ends and exits normally", w.title())
+			w.setStatus(WorkloadExited, nil)
+		}
+		if !restart {
+			return
+		}
+		select {
+		case <-w.stopC:
+			w.setStatus(WorkloadStopped, err)
+			return
+		case <-time.After(restartDelay):
+		}
+		w.mu.Lock()
+		w.restarts++
+		w.status = WorkloadRunning
+		w.mu.Unlock()
+		log.Logger.Infof("[%s] restart load, %d time(s)", w.title(), w.restarts)
+	}
+}
+
+// runRunner appends the samples and the summary of the built-in workload to the log, the samples are sent without parsing the log.
+func (w *Workload) runRunner(ctx context.Context, name string, c Channel) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum, err := w.Runner.Exec(ctx, f, func(s bench.Sample) {
+		s.Name = w.Name
+		Ld.addSample(s)
+		c.SampleC <- s
+	})
+	if sum != nil {
+		w.mu.Lock()
+		w.summary = sum
+		w.mu.Unlock()
+	}
+	return err
+}
+
+// check verifies the data of the running workload and appends the result to its log,
+// it does nothing if Check is not set or the runner has nothing to check.
+func (w *Workload) check(ctx context.Context) error {
+	checker, ok := w.Runner.(bench.Checker)
+	if !w.Check || !ok || !w.isRunning() {
+		return nil
+	}
+	w.mu.Lock()
+	name := w.logPath
+	w.mu.Unlock()
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
 	}
-	log.Logger.Info(action)
-	l.IsOver = true
+	defer f.Close()
+	log.Logger.Infof("[%s] check the data", w.title())
+	if err := checker.Check(ctx, f); err != nil {
+		return fmt.Errorf("[%s] check failed: %w", w.title(), err)
+	}
+	return nil
+}
+
+// stop kills the command and waits for it, it does nothing if the workload is not started.
+func (w *Workload) stop() {
+	w.mu.Lock()
+	stopC, doneC := w.stopC, w.doneC
+	w.mu.Unlock()
+	if stopC == nil {
+		return
+	}
+	select {
+	case <-stopC:
+	default:
+		close(stopC)
+	}
+	<-doneC
+}
+
+// wait blocks until the workload exits and returns its error.
+func (w *Workload) wait() error {
+	<-w.doneC
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
 }
 
-func (l *Load) captureLoadLog(name string, errC chan error, ldC chan string) {
+// captureLoadLog sends the samples parsed from the report lines to SampleC and the other lines to LdC,
+// the lines of a named workload are prefixed with its name, the ones before offset are of the previous phase.
+func (w *Workload) captureLoadLog(name string, offset int64, c Channel) {
 	time.Sleep(1 * time.Second)
-	t, err := log.Track(name)
+	t, err := log.TrackFrom(name, offset)
 	if err != nil {
-		errC <- err
+		c.ErrC <- err
+		return
 	}
-	for l := range t.Lines {
-		ldC <- l.Text
+	// the lines left in the log are read before the tail stops, the next start tracks the log again
+	go func(doneC chan struct{}) {
+		<-doneC
+		t.StopAtEOF()
+	}(w.doneC)
+	var p bench.Parser
+	for line := range t.Lines {
+		if s, ok := p.Parse(line.Text, time.Now()); ok {
+			s.Name = w.Name
+			Ld.addSample(s)
+			c.SampleC <- s
+			continue
+		}
+		if w.Name != "" {
+			c.LdC <- fmt.Sprintf("[%s] %s", w.Name, line.Text)
+			continue
+		}
+		c.LdC <- line.Text
 	}
 }
 
+// find returns the workload of the name started by the job, or the configured one.
+func (l *Load) find(name string) (*Workload, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, ws := range [][]*Workload{l.started, l.Workloads} {
+		for _, w := range ws {
+			if w.Name == name {
+				return w, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// start runs the workloads which are not running yet, the logs are written into dir.
+func (l *Load) start(dir string, c Channel, ws ...*Workload) error {
+	for _, w := range ws {
+		if w.isRunning() {
+			continue
+		}
+		if err := w.start(filepath.Join(dir, w.logName()), c, c.ErrC); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		l.started = appendWorkload(l.started, w)
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// run starts the workload and waits for it, the output is written to name.
+func (l *Load) run(name string, c Channel, w *Workload) error {
+	if err := w.start(name, c, nil); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.started = appendWorkload(l.started, w)
+	l.mu.Unlock()
+	return w.wait()
+}
+
+// stop stops the running workloads, or only the ones given.
+func (l *Load) stop(ws ...*Workload) {
+	if len(ws) == 0 {
+		l.mu.Lock()
+		ws = append(ws, l.started...)
+		l.mu.Unlock()
+	}
+	for _, w := range ws {
+		w.stop()
+	}
+}
+
+// running reports whether any workload started by the job is still running.
+func (l *Load) running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, w := range l.started {
+		if w.isRunning() {
+			return true
+		}
+	}
+	return false
+}
+
+// check verifies the data of the running workloads, e.g. the consistency of tpcc after a fault.
+func (l *Load) check(ctx context.Context) error {
+	l.mu.Lock()
+	ws := append([]*Workload(nil), l.started...)
+	l.mu.Unlock()
+	var errs []string
+	for _, w := range ws {
+		if err := w.check(ctx); err != nil {
+			log.Logger.Error(err.Error())
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (l *Load) results() []WorkloadResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var rs []WorkloadResult
+	for _, w := range l.started {
+		rs = append(rs, w.result())
+	}
+	return rs
+}
+
+func appendWorkload(ws []*Workload, w *Workload) []*Workload {
+	for _, o := range ws {
+		if o == w {
+			return ws
+		}
+	}
+	return append(ws, w)
+}
+
+func (l *Load) addSample(s bench.Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples = append(l.samples, s)
+}
+
+// Samples returns the samples since the job started.
+func (l *Load) Samples() []bench.Sample {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]bench.Sample(nil), l.samples...)
+}
+
+func writeSamples(name string, samples []bench.Sample) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"workload", "time", "tps", "qps", "percentile", "latency_ms", "errors_per_second"}); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if err := w.Write([]string{
+			s.Name,
+			s.Time.Format(time.RFC3339),
+			strconv.FormatFloat(s.TPS, 'f', 2, 64),
+			strconv.FormatFloat(s.QPS, 'f', 2, 64),
+			s.Percentile,
+			strconv.FormatFloat(s.Latency, 'f', 2, 64),
+			strconv.FormatFloat(s.Errors, 'f', 2, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (l *Load) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.started = nil
+	l.samples = nil
+}
+
 func cntDown(msg string, cnt int64) {
 	if cnt == 0 {
 		log.Logger.Infof("[%s] right now...", msg)
@@ -0,0 +1,280 @@
+package job
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/operator"
+	"pictorial/ssh"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// the actions of a log rule.
+const (
+	// RuleFail fails the case if a line matches.
+	RuleFail = "fail"
+	// RuleExpect fails the case if no line matches.
+	RuleExpect = "expect"
+	// RuleCount counts the lines of the job by the message into log_rules.txt.
+	RuleCount = "count"
+)
+
+// LogRule is [[logs.rule]], the logs of the components are scanned by the pattern, an extended regular expression,
+// in the window of each case, or of the job for count.
+type LogRule struct {
+	Name    string
+	Pattern string
+	Action  string
+	// Components are the roles whose logs are scanned, tidb, pd and tikv if it is empty.
+	Components []string
+	// Cases are the operators of the cases the rule applies to, e.g. kill, all of them if it is empty.
+	Cases []string
+	// Targets are the components or the targets of the cases, e.g. tikv or pd-leader, all of them if it is empty.
+	Targets []string
+}
+
+// LogRules are the built-in rules, a rule of the config with the same name replaces one of them.
+var LogRules = []LogRule{
+	{Name: "panic", Pattern: `\[FATAL\]|^panic: |panicked at`, Action: RuleFail},
+	{Name: "error", Pattern: `\[ERROR\]`, Action: RuleCount},
+	{
+		Name:       "pd leader",
+		Pattern:    `campaign (PD|pd) leader ok|PD leader is ready to serve`,
+		Action:     RuleExpect,
+		Components: []string{"pd"},
+		Cases:      []string{operator.GetOTypeValue(operator.Kill), operator.GetOTypeValue(operator.Crash)},
+		Targets:    []string{comp.PDLeader},
+	},
+	{
+		Name:       "region split",
+		Pattern:    `split region|region split|batch split`,
+		Action:     RuleExpect,
+		Components: []string{"tikv", "pd"},
+		Cases:      []string{operator.GetOTypeValue(operator.DataDistribution)},
+	},
+}
+
+var defaultRuleComponents = []string{"tidb", "pd", "tikv"}
+
+const (
+	logRules = "log_rules.txt"
+	// ruleLines are the matched lines kept for a rule of an instance.
+	ruleLines = 3
+	// ruleMessages are the messages of a count rule written for an instance.
+	ruleMessages = 10
+)
+
+func (r *LogRule) components() []string {
+	if len(r.Components) == 0 {
+		return defaultRuleComponents
+	}
+	return r.Components
+}
+
+// applies is true if the rule checks the case.
+func (r *LogRule) applies(c *CaseResult) bool {
+	return r.Action != RuleCount && (len(r.Cases) == 0 || contains(r.Cases, c.OType)) &&
+		(len(r.Targets) == 0 || contains(r.Targets, c.Component) || contains(r.Targets, c.ID))
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// logInstance is an instance whose logs are scanned.
+type logInstance struct {
+	role string
+	addr string
+	host string
+	dir  string
+}
+
+// ruleMatch is the lines of an instance which match a rule.
+type ruleMatch struct {
+	instance string
+	ssh.LogMatch
+}
+
+// scanLogs scans the logs of the components of the rules in the window, each instance is scanned in one pass
+// and the hosts in parallel. The matches are in the order of the rules, an instance which fails is skipped with
+// a warning.
+func (j *Job) scanLogs(rules []LogRule, from, to time.Time) [][]ruleMatch {
+	var instances []logInstance
+	byHost := make(map[string][]int)
+	var hosts []string
+	for _, role := range []string{"tidb", "pd", "tikv", "tiflash"} {
+		for _, c := range j.components[cTypeByName[role]] {
+			i := logInstance{
+				role: role,
+				addr: net.JoinHostPort(c.Host, comp.CleanLeaderFlag(c.Port)),
+				host: c.Host,
+				dir:  logDir(role, c),
+			}
+			if _, ok := byHost[i.host]; !ok {
+				hosts = append(hosts, i.host)
+			}
+			byHost[i.host] = append(byHost[i.host], len(instances))
+			instances = append(instances, i)
+		}
+	}
+	matches := make([][]ruleMatch, len(rules))
+	found := make([][][]ssh.LogMatch, len(hosts))
+	hostIdx := make(map[string]int)
+	for n, h := range hosts {
+		hostIdx[h] = n
+	}
+	results := ssh.S.Parallel(hosts, func(host string) ([]byte, error) {
+		var errs []string
+		for _, idx := range byHost[host] {
+			in := instances[idx]
+			var patterns []string
+			for _, r := range rules {
+				if contains(r.components(), in.role) {
+					patterns = append(patterns, r.Pattern)
+				}
+			}
+			if len(patterns) == 0 {
+				found[hostIdx[host]] = append(found[hostIdx[host]], nil)
+				continue
+			}
+			ms, err := ssh.S.ScanLogs(host, in.dir, from, to, patterns, ruleLines)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s %s: %v", in.role, in.addr, err))
+			}
+			found[hostIdx[host]] = append(found[hostIdx[host]], ms)
+		}
+		if len(errs) != 0 {
+			return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return nil, nil
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			log.Logger.Warnf("[logs] scan %s failed: %s", r.Host, r.Err.Error())
+		}
+	}
+	for n, h := range hosts {
+		for k, idx := range byHost[h] {
+			in := instances[idx]
+			ms := found[n][k]
+			if ms == nil {
+				continue
+			}
+			p := 0
+			for i, r := range rules {
+				if !contains(r.components(), in.role) {
+					continue
+				}
+				matches[i] = append(matches[i], ruleMatch{instance: fmt.Sprintf("%s %s", in.role, in.addr), LogMatch: ms[p]})
+				p++
+			}
+		}
+	}
+	return matches
+}
+
+// checkLogs fails the finished cases by the fail and expect rules in their windows, and counts the lines of the
+// count rules in the window of the job into log_rules.txt.
+func (j *Job) checkLogs() {
+	if !Logs.Check || len(LogRules) == 0 {
+		return
+	}
+	for _, c := range j.report.finished() {
+		if o, ok := operator.GetOTypeByValue(c.OType); ok && isScriptJob(o) {
+			continue
+		}
+		var rules []LogRule
+		for _, r := range LogRules {
+			if r.applies(c) {
+				rules = append(rules, r)
+			}
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		var failed []string
+		for i, ms := range j.scanLogs(rules, c.Start, c.End) {
+			r := rules[i]
+			if len(ms) == 0 {
+				continue
+			}
+			var total int
+			var first string
+			for _, m := range ms {
+				total += m.Count
+				if first == "" && len(m.Lines) != 0 {
+					first = fmt.Sprintf("%s: %s", m.instance, m.Lines[0])
+				}
+			}
+			switch {
+			case r.Action == RuleFail && total != 0:
+				failed = append(failed, fmt.Sprintf("log rule %s: %d line(s), %s", r.Name, total, first))
+			case r.Action == RuleExpect && total == 0:
+				failed = append(failed, fmt.Sprintf("log rule %s: no line matches %s", r.Name, r.Pattern))
+			}
+		}
+		if len(failed) != 0 {
+			j.report.failLogs(c, strings.Join(failed, "; "))
+			log.Logger.Errorf("[logs] %s: %s", c.ID, strings.Join(failed, "; "))
+		}
+	}
+	var counts []LogRule
+	for _, r := range LogRules {
+		if r.Action == RuleCount {
+			counts = append(counts, r)
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	name := filepath.Join(j.resultPath, logRules)
+	if err := writeRuleCounts(name, counts, j.scanLogs(counts, j.begin, time.Now())); err != nil {
+		log.Logger.Warnf("[logs] write %s failed: %s", name, err.Error())
+		return
+	}
+	j.report.attach(name)
+}
+
+// writeRuleCounts writes the count of each instance and its most frequent messages.
+func writeRuleCounts(name string, rules []LogRule, matches [][]ruleMatch) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := tabwriter.NewWriter(f, 0, 0, 2, ' ', 0)
+	for i, r := range rules {
+		fmt.Fprintf(w, "%s\t%s\n", r.Name, r.Pattern)
+		for _, m := range matches[i] {
+			fmt.Fprintf(w, "  %s\t%d\n", m.instance, m.Count)
+			msgs := make([]string, 0, len(m.Messages))
+			for msg := range m.Messages {
+				msgs = append(msgs, msg)
+			}
+			sort.Slice(msgs, func(a, b int) bool {
+				if m.Messages[msgs[a]] != m.Messages[msgs[b]] {
+					return m.Messages[msgs[a]] > m.Messages[msgs[b]]
+				}
+				return msgs[a] < msgs[b]
+			})
+			if len(msgs) > ruleMessages {
+				msgs = msgs[:ruleMessages]
+			}
+			for _, msg := range msgs {
+				fmt.Fprintf(w, "    %s\t%d\n", msg, m.Messages[msg])
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,137 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/gizak/termui/v3/widgets"
+	"net"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/operator"
+	"pictorial/util/http"
+	"pictorial/widget"
+	"strings"
+	"time"
+)
+
+var networkCType = []comp.CType{comp.TiDB, comp.PD, comp.TiKV, comp.TiFlash}
+
+// peers returns the hosts which the fault is injected between with the host.
+// network_delay is between the chosen hosts if more than one host is chosen, otherwise it is the same with network_isolation.
+func (j *Job) peers(o operator.OType, host string) []string {
+	switch o {
+	case operator.NetworkIsolation:
+		return j.clusterHosts(func(h string) bool { return h != host })
+	case operator.NetworkDelay:
+		chosen := j.selectedHosts()
+		if len(chosen) > 1 {
+			return excludeLocal(chosen, func(h string) bool { return h != host })
+		}
+		return j.clusterHosts(func(h string) bool { return h != host })
+	}
+	return nil
+}
+
+// clusterHosts returns the hosts of tidb, pd, tikv and tiflash, the hosts of tipoc itself are excluded to keep ssh alive.
+func (j *Job) clusterHosts(keep func(string) bool) []string {
+	var hosts []string
+	for _, cType := range networkCType {
+		for _, c := range j.components[cType] {
+			hosts = append(hosts, c.Host)
+		}
+	}
+	return excludeLocal(hosts, keep)
+}
+
+func (j *Job) selectedHosts() []string {
+	var hosts []string
+	j.selected.Walk(func(node *widgets.TreeNode) bool {
+		e := widget.ChangeToExample(node)
+		addr := strings.Trim(e.String(), comp.Leader)
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			hosts = append(hosts, host)
+		}
+		return true
+	})
+	return hosts
+}
+
+func excludeLocal(hosts []string, keep func(string) bool) []string {
+	local, err := http.GetIpList()
+	if err != nil {
+		log.Logger.Warnf("get local ip failed: %s", err.Error())
+	}
+	visited := make(map[string]bool)
+	for _, ip := range local {
+		visited[ip] = true
+	}
+	var r []string
+	for _, h := range hosts {
+		if visited[h] || !keep(h) {
+			continue
+		}
+		visited[h] = true
+		r = append(r, h)
+	}
+	return r
+}
+
+// runPartition isolates the tikv hosts with the label from the rest of the cluster.
+func (j *Job) runPartition(ctx context.Context) {
+	var cnt int
+	ov := operator.GetOTypeValue(operator.NetworkPartition)
+	j.selected.Walk(func(i *widgets.TreeNode) bool {
+		cnt++
+		targetLabel := i.Value.String()
+		rc := j.report.begin(targetLabel, operator.NetworkPartition, comp.TiKV, targetLabel)
+		inside := make(map[string]bool)
+		var side []string
+		for _, kv := range j.components[comp.TiKV] {
+			for _, v := range kv.Labels {
+				if targetLabel == v && !inside[kv.Host] {
+					inside[kv.Host] = true
+					side = append(side, kv.Host)
+					break
+				}
+			}
+		}
+		others := j.clusterHosts(func(h string) bool { return !inside[h] })
+		j.faultHost(side...)
+		faultAt := time.Now()
+		var failed []string
+		var isolated []operator.Operator
+		for _, host := range side {
+			b := operator.Builder{
+				Host:  host,
+				OType: operator.NetworkPartition,
+				CType: comp.TiKV,
+				Ctx:   ctx,
+				Peers: others,
+			}
+			r, err := b.Build()
+			if err == nil {
+				// the rules added before a failure are removed by the recovery as well.
+				isolated = append(isolated, r)
+				err = r.Execute()
+			}
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", host, err))
+				log.Logger.Errorf("[%s] %s failed: %v", ov, host, err)
+			}
+		}
+		log.Logger.Infof("[%s] %s %v from %v", ov, targetLabel, side, others)
+		if !j.noRecover {
+			if err := j.recover(ctx, rc, faultAt, isolated...); err != nil {
+				failed = append(failed, err.Error())
+				log.Logger.Errorf("[%s] %s failed: %v", ov, targetLabel, err)
+			}
+		}
+		if len(failed) != 0 {
+			j.report.finish(rc, StatusFail, strings.Join(failed, "; "))
+		} else {
+			j.report.finish(rc, StatusPass, "")
+		}
+		j.progress(cnt)
+		return true
+	})
+}
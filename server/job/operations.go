@@ -1,14 +1,24 @@
 package job
 
 import (
+	"fmt"
+	"net"
 	"path/filepath"
 	"pictorial/comp"
 	"pictorial/log"
 	"pictorial/mysql"
 	"pictorial/operator"
 	"pictorial/ssh"
+	"strings"
+	"time"
 )
 
+// generalLogPattern is the general log of the insert, tidb logs it as [GENERAL_LOG] ... [sql="INSERT INTO ..."].
+const generalLogPattern = `GENERAL_LOG.*INSERT INTO poc\.test_general_log`
+
+// generalLogWait is how long the general log is polled, tidb flushes its log asynchronously.
+const generalLogWait = 10 * time.Second
+
 func (j *Job) runGeneralLogJob() error {
 	ov := operator.GetOTypeValue(operator.GeneralLog)
 
@@ -24,15 +34,26 @@ func (j *Job) runGeneralLogJob() error {
 	}
 	j.writeResultFile(ov, 1, 0, output)
 
-	var deployPath string
-	for _, tidb := range j.components[comp.TiDB] {
-		if tidb.Host == mysql.M.Host && tidb.Port == mysql.M.Port {
-			deployPath = tidb.DeployPath
+	// the statements are logged by the tidb which serves the connection, which is unknown behind a proxy.
+	tidbs := j.components[comp.TiDB]
+	deadline := time.Now().Add(generalLogWait)
+	for {
+		var searched []string
+		for _, tidb := range tidbs {
+			logPath := filepath.Join(logDir("tidb", tidb), "tidb.log")
+			out, err := ssh.S.GrepTailN(tidb.Host, generalLogPattern, logPath, 1)
+			if err != nil {
+				return err
+			}
+			if len(out) != 0 {
+				log.Logger.Infof("[%s] %s: %s", ov, net.JoinHostPort(tidb.Host, tidb.Port), strings.TrimSpace(string(out)))
+				return nil
+			}
+			searched = append(searched, fmt.Sprintf("%s:%s", tidb.Host, logPath))
 		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[%s] the insert is not in the general log of %s", ov, strings.Join(searched, ", "))
+		}
+		time.Sleep(time.Second)
 	}
-	logPath := filepath.Join(deployPath, "log", "tidb.log")
-	if _, err := ssh.S.GrepTailN(mysql.M.Host, logPath, 2); err != nil {
-		return err
-	}
-	return nil
 }
@@ -0,0 +1,355 @@
+package job
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pictorial/log"
+	"pictorial/mysql"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Probe writes and reads timestamped rows continuously during the load job,
+// the unavailability window (rto), the lost acknowledged writes (rpo) and the errors are summarized after the job.
+type Probe struct {
+	Enable   bool
+	Interval time.Duration
+	Timeout  time.Duration
+	// Window is the minimum gap between two successful operations to be counted as unavailable.
+	Window time.Duration
+}
+
+var Pb = Probe{
+	Enable:   true,
+	Interval: 100 * time.Millisecond,
+	Timeout:  2 * time.Second,
+	Window:   time.Second,
+}
+
+const (
+	probeWrite   = "write"
+	probeRead    = "read"
+	probeSummary = "probe.txt"
+	probeDetail  = "probe.csv"
+	probeTable   = "poc.probe"
+	// probeNotFound marks a read which succeeded without the acknowledged row.
+	probeNotFound = "not found"
+)
+
+type probeOp struct {
+	kind    string
+	id      int64
+	start   time.Time
+	end     time.Time
+	errCode uint16
+	errMsg  string
+}
+
+func (o probeOp) ok() bool {
+	return o.errMsg == ""
+}
+
+type window struct {
+	kind  string
+	start time.Time
+	end   time.Time
+}
+
+func (w window) duration() time.Duration {
+	return w.end.Sub(w.start)
+}
+
+type prober struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	ops    []probeOp
+}
+
+func startProbe() (*prober, error) {
+	if _, err := mysql.M.ExecuteSQL(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGINT PRIMARY KEY, ts DATETIME(6))", probeTable)); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &prober{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go p.run(ctx)
+	log.Logger.Infof("[probe] start, interval: %s, timeout: %s", Pb.Interval, Pb.Timeout)
+	return p, nil
+}
+
+func (p *prober) run(ctx context.Context) {
+	defer close(p.done)
+	s, err := mysql.M.NewSessionWithTimeout(mysql.M.User, mysql.M.Password, Pb.Timeout)
+	if err != nil {
+		log.Logger.Warnf("[probe] connect failed: %s", err.Error())
+	}
+	defer s.Close()
+	ticker := time.NewTicker(Pb.Interval)
+	defer ticker.Stop()
+	var id, acked int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		id++
+		w := p.exec(s, probeWrite, id, fmt.Sprintf("INSERT INTO %s VALUES (%d, NOW(6))", probeTable, id))
+		if w.ok() {
+			acked = id
+		}
+		if acked == 0 {
+			continue
+		}
+		p.exec(s, probeRead, acked, fmt.Sprintf("SELECT id FROM %s WHERE id = %d", probeTable, acked))
+	}
+}
+
+func (p *prober) exec(s *mysql.Session, kind string, id int64, sql string) probeOp {
+	op := probeOp{
+		kind:  kind,
+		id:    id,
+		start: time.Now(),
+	}
+	r := s.Execute(sql)
+	op.end = time.Now()
+	switch {
+	case r.Failed():
+		op.errCode = r.ErrCode
+		op.errMsg = r.ErrMsg
+	case kind == probeRead && len(r.Rows) == 0:
+		op.errMsg = probeNotFound
+	}
+	p.ops = append(p.ops, op)
+	return op
+}
+
+// stop waits for the probe goroutine, verifies the acknowledged writes and writes the summary.
+func (p *prober) stop(dir string) []string {
+	p.cancel()
+	<-p.done
+	lost, err := p.lost()
+	if err != nil {
+		log.Logger.Errorf("[probe] verify acknowledged writes failed: %s", err.Error())
+	}
+	summary := filepath.Join(dir, probeSummary)
+	if err := p.writeSummary(summary, lost, err); err != nil {
+		log.Logger.Errorf("[probe] write %s failed: %s", summary, err.Error())
+		return nil
+	}
+	detail := filepath.Join(dir, probeDetail)
+	if err := p.writeDetail(detail); err != nil {
+		log.Logger.Errorf("[probe] write %s failed: %s", detail, err.Error())
+		return []string{summary}
+	}
+	log.Logger.Infof("[probe] complete, summary at %s", summary)
+	return []string{summary, detail}
+}
+
+const probeVerifyRetry = 10
+
+// lost returns the acknowledged writes which can not be read after the job, the cluster may be still recovering so it retries.
+func (p *prober) lost() ([]probeOp, error) {
+	var rs []*mysql.StatementResult
+	var err error
+	for i := 0; i < probeVerifyRetry; i++ {
+		rs, err = mysql.M.ExecuteScript(fmt.Sprintf("SELECT id FROM %s", probeTable), mysql.M.User, mysql.M.Password)
+		if err == nil && len(rs) == 1 && !rs[0].Failed() {
+			break
+		}
+		if err == nil && len(rs) == 1 {
+			err = fmt.Errorf("ERROR %d: %s", rs[0].ErrCode, rs[0].ErrMsg)
+		}
+		time.Sleep(Pb.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]bool)
+	for _, row := range rs[0].Rows {
+		id, _ := strconv.ParseInt(row[0], 10, 64)
+		found[id] = true
+	}
+	var lost []probeOp
+	for _, o := range p.ops {
+		if o.kind == probeWrite && o.ok() && !found[o.id] {
+			lost = append(lost, o)
+		}
+	}
+	return lost, nil
+}
+
+// windows returns the gaps longer than Pb.Window between two successful operations of the kind,
+// the gap starts at the end of the last success and ends at the end of the next success.
+func (p *prober) windows(kind string) []window {
+	var ws []window
+	var last, end time.Time
+	for _, o := range p.ops {
+		if o.kind != kind {
+			continue
+		}
+		end = o.end
+		if !o.ok() {
+			continue
+		}
+		if !last.IsZero() && o.end.Sub(last)-Pb.Interval >= Pb.Window {
+			ws = append(ws, window{kind: kind, start: last, end: o.end})
+		}
+		last = o.end
+	}
+	// still unavailable at the end of the probe
+	if !last.IsZero() && end.Sub(last) >= Pb.Window {
+		ws = append(ws, window{kind: kind, start: last, end: end})
+	}
+	return ws
+}
+
+func rto(ws []window) time.Duration {
+	var d time.Duration
+	for _, w := range ws {
+		if w.duration() > d {
+			d = w.duration()
+		}
+	}
+	return d
+}
+
+// rpo is the data loss window, from the ack of the last write which is still readable before the first lost one
+// to the ack of the last lost write, or from the first lost write if none is readable. It is at least the interval
+// of the probe, so a single lost write is not reported as no loss.
+func (p *prober) rpo(lost []probeOp) time.Duration {
+	first, last := lost[0], lost[len(lost)-1]
+	from := first.start
+	for _, o := range p.ops {
+		if o.kind == probeWrite && o.ok() && o.id < first.id {
+			from = o.end
+		}
+	}
+	if d := last.end.Sub(from); d > Pb.Interval {
+		return d
+	}
+	return Pb.Interval
+}
+
+func (p *prober) writeSummary(name string, lost []probeOp, verifyErr error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tw := tabwriter.NewWriter(f, 0, 0, 2, ' ', 0)
+
+	var all []window
+	fmt.Fprintln(tw, "kind\ttotal\tok\tfailed\trto\tunavailable\t")
+	for _, kind := range []string{probeWrite, probeRead} {
+		var total, ok int
+		for _, o := range p.ops {
+			if o.kind != kind {
+				continue
+			}
+			total++
+			if o.ok() {
+				ok++
+			}
+		}
+		ws := p.windows(kind)
+		all = append(all, ws...)
+		var sum time.Duration
+		for _, w := range ws {
+			sum += w.duration()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\t\n", kind, total, ok, total-ok, rto(ws).Round(time.Millisecond), sum.Round(time.Millisecond))
+	}
+
+	fmt.Fprintln(tw)
+	switch {
+	case verifyErr != nil:
+		fmt.Fprintf(tw, "rpo\tunknown, %s\t\n", verifyErr.Error())
+	case len(lost) == 0:
+		fmt.Fprintln(tw, "rpo\t0\t")
+		fmt.Fprintln(tw, "lost acknowledged writes\t0\t")
+	default:
+		fmt.Fprintf(tw, "rpo\t%s\t\n", p.rpo(lost).Round(time.Millisecond))
+		fmt.Fprintf(tw, "lost acknowledged writes\t%d\t\n", len(lost))
+		fmt.Fprintf(tw, "lost range\t[%d, %d]\t\n", lost[0].id, lost[len(lost)-1].id)
+	}
+
+	if len(all) != 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "kind\tunavailable from\tto\tduration\t")
+		sort.Slice(all, func(i, j int) bool { return all[i].start.Before(all[j].start) })
+		for _, w := range all {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", w.kind, w.start.Format(time.RFC3339Nano), w.end.Format(time.RFC3339Nano), w.duration().Round(time.Millisecond))
+		}
+	}
+
+	type errCount struct {
+		kind  string
+		code  uint16
+		msg   string
+		count int
+	}
+	counts := make(map[string]*errCount)
+	for _, o := range p.ops {
+		if o.ok() {
+			continue
+		}
+		k := fmt.Sprintf("%s/%d", o.kind, o.errCode)
+		if o.errCode == 0 {
+			k = fmt.Sprintf("%s/%s", o.kind, o.errMsg)
+		}
+		if _, ok := counts[k]; !ok {
+			counts[k] = &errCount{kind: o.kind, code: o.errCode, msg: o.errMsg}
+		}
+		counts[k].count++
+	}
+	if len(counts) != 0 {
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "kind\terror code\tcount\tfirst message\t")
+		for _, k := range keys {
+			c := counts[k]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t\n", c.kind, c.code, c.count, strings.ReplaceAll(c.msg, "\n", " "))
+		}
+	}
+	return tw.Flush()
+}
+
+func (p *prober) writeDetail(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"kind", "id", "start", "end", "latency_ms", "error_code", "error"}); err != nil {
+		return err
+	}
+	for _, o := range p.ops {
+		if err := w.Write([]string{
+			o.kind,
+			strconv.FormatInt(o.id, 10),
+			o.start.Format(time.RFC3339Nano),
+			o.end.Format(time.RFC3339Nano),
+			strconv.FormatInt(o.end.Sub(o.start).Milliseconds(), 10),
+			strconv.Itoa(int(o.errCode)),
+			o.errMsg,
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
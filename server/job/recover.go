@@ -0,0 +1,102 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/operator"
+	"strings"
+	"time"
+)
+
+var recoverJob = []operator.OType{
+	operator.Kill,
+	operator.Crash,
+	operator.Reboot,
+	operator.DataCorrupted,
+	operator.Disaster,
+	operator.NetworkIsolation,
+	operator.NetworkDelay,
+	operator.NetworkPartition,
+}
+
+func isRecoverJob(o operator.OType) bool {
+	for _, oType := range recoverJob {
+		if o == oType {
+			return true
+		}
+	}
+	return false
+}
+
+// RecoverTimeout is how long to wait for the cluster healthy again after a fault.
+var RecoverTimeout = 10 * time.Minute
+
+const recoverInterval = 5 * time.Second
+
+// recover restores the faults of the operators and waits until every component of the cluster is healthy,
+// the time to recover is counted from the fault, then the data of the checked workloads are verified.
+// Every operator is recovered even if some of them fail, the errors are returned together.
+func (j *Job) recover(ctx context.Context, rc *CaseResult, since time.Time, ops ...operator.Operator) error {
+	var errs []string
+	for _, o := range ops {
+		if r, ok := o.(operator.Recoverable); ok {
+			if err := r.Recover(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("recover failed: %s", strings.Join(errs, "; "))
+	}
+	if err := j.waitHealthy(ctx, rc, since, RecoverTimeout); err != nil {
+		return err
+	}
+	return Ld.check(ctx)
+}
+
+// aftercareTimeout is how long the faults left by the job are undone for, before the ssh connections are closed.
+const aftercareTimeout = 2 * time.Minute
+
+// waitAftercare waits for the operators to undo their faults after the job is cancelled.
+func waitAftercare() {
+	if !operator.WaitAftercare(aftercareTimeout) {
+		log.Logger.Errorf("[recover] the faults are not undone in %s, check the hosts", aftercareTimeout)
+	}
+}
+
+// restore undoes what a failed fault has done, e.g. the iptables rules added before the failure.
+func restore(o operator.Operator) {
+	if r, ok := o.(operator.Recoverable); ok {
+		if err := r.Recover(); err != nil {
+			log.Logger.Errorf("[recover] %s", err.Error())
+		}
+	}
+}
+
+// waitHealthy polls the cluster until every component is healthy and records the time to recover.
+func (j *Job) waitHealthy(ctx context.Context, rc *CaseResult, since time.Time, d time.Duration) error {
+	m := comp.Mapping{Map: j.components}
+	timeout := time.After(d)
+	ticker := time.NewTicker(recoverInterval)
+	defer ticker.Stop()
+	var unhealthy []string
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("recover cancelled: %s", strings.Join(unhealthy, "; "))
+		case <-timeout:
+			return fmt.Errorf("not recovered in %s: %s", d, strings.Join(unhealthy, "; "))
+		case <-ticker.C:
+		}
+		unhealthy = m.Unhealthy(since)
+		if len(unhealthy) == 0 {
+			ttr := time.Since(since)
+			j.report.recovered(rc, ttr)
+			log.Logger.Infof("[recover] %s recovered in %.1fs", rc.ID, ttr.Seconds())
+			return nil
+		}
+		log.Logger.Debugf("[recover] %s waiting: %s", rc.ID, strings.Join(unhealthy, "; "))
+	}
+}
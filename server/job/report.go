@@ -0,0 +1,434 @@
+package job
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pictorial/bench"
+	"pictorial/comp"
+	"pictorial/operator"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	// StatusUnverified is a script case which ran without errors but has no golden output to diff against.
+	StatusUnverified Status = "unverified"
+)
+
+const (
+	reportJson  = "report.json"
+	reportJUnit = "report.xml"
+)
+
+type Report struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"duration"`
+	Pass     int       `json:"pass"`
+	Warn     int       `json:"warn"`
+	Fail     int       `json:"fail"`
+	// Unverified are the script cases without a golden output, they are not counted as failed.
+	Unverified int              `json:"unverified"`
+	Cases      []*CaseResult    `json:"cases"`
+	Workloads  []WorkloadResult `json:"workloads,omitempty"`
+	Artifacts  []string         `json:"artifacts,omitempty"`
+
+	mu      sync.Mutex
+	current *CaseResult
+	samples []bench.Sample
+}
+
+type CaseResult struct {
+	ID        string    `json:"id"`
+	OType     string    `json:"otype"`
+	Component string    `json:"component,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Duration  float64   `json:"duration"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Artifacts []string  `json:"artifacts,omitempty"`
+	// TimeToRecover is the seconds from the fault to the cluster healthy again.
+	TimeToRecover float64 `json:"time_to_recover,omitempty"`
+	// ThroughputDrop is the percentage of the lowest tps during the fault below the tps before it.
+	ThroughputDrop float64 `json:"throughput_drop,omitempty"`
+}
+
+func newReport() *Report {
+	return &Report{
+		Start: time.Now(),
+		Cases: make([]*CaseResult, 0),
+	}
+}
+
+func (r *Report) begin(id string, oType operator.OType, cType comp.CType, target string) *CaseResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &CaseResult{
+		ID:        id,
+		OType:     operator.GetOTypeValue(oType),
+		Component: comp.GetCTypeValue(cType),
+		Target:    target,
+		Start:     time.Now(),
+	}
+	r.Cases = append(r.Cases, c)
+	r.current = c
+	return c
+}
+
+// beginStep records a scenario step which is not an operator, e.g. health and assert.
+func (r *Report) beginStep(id, kind string) *CaseResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &CaseResult{
+		ID:    id,
+		OType: kind,
+		Start: time.Now(),
+	}
+	r.Cases = append(r.Cases, c)
+	r.current = c
+	return c
+}
+
+// attach binds an artifact to the running case, cases are executed one by one.
+func (r *Report) attach(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		r.Artifacts = append(r.Artifacts, path)
+		return
+	}
+	r.current.Artifacts = append(r.current.Artifacts, path)
+}
+
+func (r *Report) finish(c *CaseResult, status Status, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.End = time.Now()
+	c.Duration = c.End.Sub(c.Start).Seconds()
+	c.Status = status
+	c.Error = errMsg
+	if r.current == c {
+		r.current = nil
+	}
+}
+
+// finished are the cases which have finished.
+func (r *Report) finished() []*CaseResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var cs []*CaseResult
+	for _, c := range r.Cases {
+		if !c.End.IsZero() {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// failLogs fails a finished case by the log rules, the message is added to its error.
+func (r *Report) failLogs(c *CaseResult, msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.Status = StatusFail
+	if c.Error != "" {
+		msg = c.Error + "; " + msg
+	}
+	c.Error = msg
+}
+
+func (r *Report) recovered(c *CaseResult, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.TimeToRecover = d.Seconds()
+}
+
+// baselineWindow is the time before a fault whose average tps is the baseline of the drop.
+const baselineWindow = time.Minute
+
+// workloads keeps the status of the workloads at the end of the job.
+func (r *Report) workloads(rs []WorkloadResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Workloads = rs
+}
+
+// throughput keeps the samples of the load and calculates the drop of each fault, which is a case with a target,
+// the drop of a case is the largest one of the workloads.
+func (r *Report) throughput(samples []bench.Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = samples
+	groups := groupSamples(samples)
+	for _, c := range r.Cases {
+		if c.Target == "" || c.End.IsZero() {
+			continue
+		}
+		for _, g := range groups {
+			if d := drop(g, c.Start, c.End); d > c.ThroughputDrop {
+				c.ThroughputDrop = d
+			}
+		}
+	}
+}
+
+// drop is the percentage of the lowest tps between start and end below the average tps of baselineWindow before start.
+func drop(samples []bench.Sample, start, end time.Time) float64 {
+	var before, all []float64
+	lowest := -1.0
+	for _, s := range samples {
+		switch {
+		case s.Time.Before(start):
+			all = append(all, s.TPS)
+			if start.Sub(s.Time) <= baselineWindow {
+				before = append(before, s.TPS)
+			}
+		case !s.Time.After(end):
+			if lowest < 0 || s.TPS < lowest {
+				lowest = s.TPS
+			}
+		}
+	}
+	if len(before) == 0 {
+		before = all
+	}
+	baseline := average(before)
+	if baseline <= 0 || lowest < 0 || lowest >= baseline {
+		return 0
+	}
+	return (baseline - lowest) / baseline * 100
+}
+
+// groupSamples splits the samples by the workload in the order of the first sample.
+func groupSamples(samples []bench.Sample) [][]bench.Sample {
+	idx := make(map[string]int)
+	var groups [][]bench.Sample
+	for _, s := range samples {
+		i, ok := idx[s.Name]
+		if !ok {
+			i = len(groups)
+			idx[s.Name] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	return groups
+}
+
+func average(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
+
+func (r *Report) finishErr(c *CaseResult, err error) {
+	if err != nil {
+		r.finish(c, StatusFail, err.Error())
+		return
+	}
+	r.finish(c, StatusPass, "")
+}
+
+func (r *Report) failed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cnt := 0
+	for _, c := range r.Cases {
+		if c.Status != StatusPass && c.Status != StatusUnverified {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// collect lists the loose artifacts of the result directory, e.g. load.log, shell.log and images.
+func (r *Report) collect(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	owned := make(map[string]bool)
+	for _, a := range r.Artifacts {
+		owned[a] = true
+	}
+	for _, c := range r.Cases {
+		for _, a := range c.Artifacts {
+			owned[a] = true
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == reportJson || e.Name() == reportJUnit || e.Name() == reportHTML {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		if !owned[p] {
+			r.Artifacts = append(r.Artifacts, p)
+		}
+	}
+	sort.Strings(r.Artifacts)
+}
+
+func (r *Report) write(dir string) error {
+	r.mu.Lock()
+	r.End = time.Now()
+	r.Duration = r.End.Sub(r.Start).Seconds()
+	r.Pass, r.Warn, r.Fail, r.Unverified = 0, 0, 0, 0
+	for _, c := range r.Cases {
+		switch c.Status {
+		case StatusPass:
+			r.Pass++
+		case StatusWarn:
+			r.Warn++
+		case StatusUnverified:
+			r.Unverified++
+		default:
+			c.Status = StatusFail
+			r.Fail++
+		}
+	}
+	r.mu.Unlock()
+	if err := r.writeJson(filepath.Join(dir, reportJson)); err != nil {
+		return err
+	}
+	if err := r.writeJUnit(filepath.Join(dir, reportJUnit)); err != nil {
+		return err
+	}
+	return r.writeHTML(filepath.Join(dir, reportHTML))
+}
+
+func (r *Report) writeJson(name string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0644)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func (r *Report) writeJUnit(name string) error {
+	suites := junitSuites{
+		Name:     r.Name,
+		Tests:    len(r.Cases),
+		Failures: r.Warn + r.Fail,
+		Skipped:  r.Unverified,
+		Time:     seconds(r.Duration),
+	}
+	idx := make(map[string]int)
+	for _, c := range r.Cases {
+		i, ok := idx[c.OType]
+		if !ok {
+			i = len(suites.Suites)
+			idx[c.OType] = i
+			suites.Suites = append(suites.Suites, junitSuite{
+				Name:      c.OType,
+				Timestamp: c.Start.Format(time.RFC3339),
+			})
+		}
+		s := &suites.Suites[i]
+		jc := junitCase{
+			Name:      c.ID,
+			ClassName: c.OType,
+			Time:      seconds(c.Duration),
+			SystemOut: strings.Join(c.Artifacts, "\n"),
+		}
+		if c.ThroughputDrop != 0 {
+			jc.SystemOut = strings.TrimSpace(fmt.Sprintf("throughput_drop: %.1f%%\n%s", c.ThroughputDrop, jc.SystemOut))
+		}
+		if c.TimeToRecover != 0 {
+			jc.SystemOut = strings.TrimSpace(fmt.Sprintf("time_to_recover: %ss\n%s", seconds(c.TimeToRecover), jc.SystemOut))
+		}
+		if c.Component != "" {
+			jc.ClassName = fmt.Sprintf("%s.%s", c.OType, c.Component)
+		}
+		switch c.Status {
+		case StatusPass:
+		case StatusUnverified:
+			jc.Skipped = &junitSkipped{Message: c.Error}
+			s.Skipped++
+		default:
+			jc.Failure = &junitFailure{
+				Type:    string(c.Status),
+				Message: c.Error,
+				Text:    c.Error,
+			}
+			s.Failures++
+		}
+		s.Tests++
+		s.Cases = append(s.Cases, jc)
+	}
+	for i := range suites.Suites {
+		var d float64
+		for _, c := range r.Cases {
+			if c.OType == suites.Suites[i].Name {
+				d += c.Duration
+			}
+		}
+		suites.Suites[i].Time = seconds(d)
+	}
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append([]byte(xml.Header), b...), 0644)
+}
+
+func seconds(d float64) string {
+	return fmt.Sprintf("%.3f", d)
+}
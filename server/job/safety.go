@@ -3,8 +3,10 @@ package job
 import (
 	"fmt"
 	"github.com/gizak/termui/v3/widgets"
-	"pictorial/log"
+	"pictorial/comp"
 	"pictorial/mysql"
+	"pictorial/operator"
+	"pictorial/widget"
 	"strings"
 )
 
@@ -17,40 +19,37 @@ const tidbUserWrongPassword = "tidb_wrong_password"
 func (j *Job) runSafety() {
 	var cnt int
 	j.selected.Walk(func(i *widgets.TreeNode) bool {
-		name := i.Value.String()
+		e := widget.ChangeToExample(i)
+		name := e.Value
+		c := j.report.begin(name, operator.SafetyScript, comp.NoBody, "")
 		scripts := j.examples[i.Value.String()]
-		var err error
-		var output []string
-		var errOutput string
+		outputs := make([][]string, len(scripts))
+		errOuts := make([]string, len(scripts))
 		for i, sql := range scripts {
+			var err error
 			user := strings.Split(sql, "\n")[0]
 			sql = strings.Trim(sql, "\n")
 			switch {
 			case strings.Contains(user, rootUser):
 				sql = strings.Trim(sql, fmt.Sprintf("%s\n", rootUser))
-				output, err = mysql.M.ExecuteForceWithOutput(sql, mysql.M.User, mysql.M.Password)
+				outputs[i], err = mysql.M.ExecuteForceWithOutput(sql, mysql.M.User, mysql.M.Password)
 			case strings.Contains(user, tidbUser):
 				sql = strings.Trim(sql, fmt.Sprintf("%s\n", tidbUser))
 				if isLoginFailureLimit(name) {
-					output, err = mysql.M.ExecuteForceWithOutput(sql, tidbUserName, tidbUserWrongPassword)
+					outputs[i], err = mysql.M.ExecuteForceWithOutput(sql, tidbUserName, tidbUserWrongPassword)
 				} else {
-					output, err = mysql.M.ExecuteForceWithOutput(sql, tidbUserName, tidbUserPassword)
+					outputs[i], err = mysql.M.ExecuteForceWithOutput(sql, tidbUserName, tidbUserPassword)
 				}
 			default:
 				err = fmt.Errorf("invalid username, please use 'root' and 'tidb_user'")
 			}
 			if err != nil {
-				errOutput = err.Error()
+				errOuts[i] = err.Error()
 			}
-			j.writeResultFile(name, len(scripts), i, output)
-		}
-		if errOutput != "" {
-			log.Logger.Infof("[warn] %s: %s", name, errOutput)
-		} else {
-			log.Logger.Infof("[pass] %s", name)
 		}
+		j.settle(c, e, outputs, errOuts)
 		cnt++
-		j.Channel.BarC <- cnt
+		j.progress(cnt)
 		return true
 	})
 
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/gizak/termui/v3/widgets"
+	"os"
+	"path/filepath"
+	"pictorial/comp"
+	"pictorial/operator"
+	"pictorial/widget"
+)
+
+var cTypeByName = map[string]comp.CType{
+	"tikv":    comp.TiKV,
+	"tidb":    comp.TiDB,
+	"tiflash": comp.TiFlash,
+	"pd":      comp.PD,
+}
+
+func (j *Job) runScaleOut(ctx context.Context) {
+	var cnt int
+	j.selected.Walk(func(node *widgets.TreeNode) bool {
+		cnt++
+		e := widget.ChangeToExample(node)
+		name := widget.GetNameByValue(e.Value)
+		if err := j.runCase(node, func() error {
+			cType, ok := cTypeByName[name]
+			if !ok {
+				return fmt.Errorf("[%s] unknown component: %s", operator.GetOTypeValue(operator.ScaleOut), name)
+			}
+			topology := filepath.Join(j.resultPath, fmt.Sprintf("%s_%s.yaml", operator.GetOTypeValue(operator.ScaleOut), name))
+			b := operator.Builder{
+				Host:     operator.ScaleOutTopology.Host,
+				OType:    operator.ScaleOut,
+				CType:    cType,
+				Topology: topology,
+				Ctx:      ctx,
+			}
+			r, err := b.Build()
+			if err != nil {
+				return err
+			}
+			err = r.Execute()
+			if _, statErr := os.Stat(topology); statErr == nil {
+				j.report.attach(topology)
+			}
+			return err
+		}); err != nil {
+			j.ErrC <- err
+		}
+		j.progress(cnt)
+		return true
+	})
+}
@@ -0,0 +1,441 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+	"net"
+	"os"
+	"path/filepath"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/mysql"
+	"pictorial/operator"
+	"pictorial/ssh"
+	"pictorial/widget"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Scenario is an ordered list of steps, e.g.
+//
+//	name: ha
+//	steps:
+//	  - load: start
+//	  - wait: 2m
+//	  - fault: kill
+//	    target: pd/leader
+//	  - health: 10m
+//	  - fault: crash
+//	    target: tikv
+//	    per: zone
+//	  - case: "3.5"
+//	  - assert:
+//	      sql: SELECT COUNT(*) FROM poc.probe
+//	      rows: [["0"]]
+//	  - render: kill
+//	  - load: stop
+type Scenario struct {
+	Name  string `yaml:"name" toml:"name"`
+	Steps []Step `yaml:"steps" toml:"steps"`
+}
+
+// Step does exactly one of load, wait, fault, health, case, assert and render.
+type Step struct {
+	// Load is start or stop, all of the workloads of [[load]] or only the one of Name.
+	// Cmd runs as the workload of Name instead of the configured one on start.
+	Load string `yaml:"load" toml:"load"`
+	Name string `yaml:"name" toml:"name"`
+	Cmd  string `yaml:"cmd" toml:"cmd"`
+	// Wait is a duration, e.g. 2m.
+	Wait string `yaml:"wait" toml:"wait"`
+	// Fault is the operator of the high availability catalog, e.g. kill, crash, disaster.
+	// Target is tikv, tikv/127.0.0.1:20160 or pd/leader, or the label value for disaster and network_partition.
+	// Per picks one target for each value of the label key.
+	// Recover restores the fault and waits for the cluster healthy, true if absent.
+	Fault   string `yaml:"fault" toml:"fault"`
+	Target  string `yaml:"target" toml:"target"`
+	Per     string `yaml:"per" toml:"per"`
+	Recover *bool  `yaml:"recover" toml:"recover"`
+	// Health waits until every component is healthy, the value is the timeout.
+	Health string `yaml:"health" toml:"health"`
+	// Case is the catalog cases the same as tipoc run --cases.
+	Case   string  `yaml:"case" toml:"case"`
+	Assert *Assert `yaml:"assert" toml:"assert"`
+	// Render is the panel set to render since the start of the scenario, e.g. kill, default for the common panels.
+	Render string `yaml:"render" toml:"render"`
+}
+
+// Assert runs the sql and compares the rows, the error code or the .result file.
+type Assert struct {
+	SQL    string     `yaml:"sql" toml:"sql"`
+	Rows   [][]string `yaml:"rows" toml:"rows"`
+	Error  uint16     `yaml:"error" toml:"error"`
+	Result string     `yaml:"result" toml:"result"`
+}
+
+const (
+	stepLoad   = "load"
+	stepWait   = "wait"
+	stepFault  = "fault"
+	stepHealth = "health"
+	stepCase   = "case"
+	stepAssert = "assert"
+	stepRender = "render"
+
+	loadStart = "start"
+	loadStop  = "stop"
+)
+
+func LoadScenario(name string) (*Scenario, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	switch filepath.Ext(name) {
+	case ".toml":
+		err = toml.Unmarshal(b, &sc)
+	default:
+		err = yaml.Unmarshal(b, &sc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse scenario %s failed: %w", name, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	for i, s := range sc.Steps {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return &sc, nil
+}
+
+// workloads are the ones the load step starts or stops, nil for all of [[load]].
+func (s Step) workloads() ([]*Workload, error) {
+	w, ok := Ld.find(s.Name)
+	if s.Cmd != "" {
+		if ok && w.Cmd == s.Cmd {
+			return []*Workload{w}, nil
+		}
+		if ok && w.isRunning() {
+			return nil, fmt.Errorf("load %s is running", w.title())
+		}
+		return []*Workload{{Name: s.Name, Cmd: s.Cmd}}, nil
+	}
+	if s.Name == "" {
+		return nil, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("load %s is not in [[load]]", s.Name)
+	}
+	return []*Workload{w}, nil
+}
+
+func (s Step) kind() []string {
+	var k []string
+	if s.Load != "" {
+		k = append(k, stepLoad)
+	}
+	if s.Wait != "" {
+		k = append(k, stepWait)
+	}
+	if s.Fault != "" {
+		k = append(k, stepFault)
+	}
+	if s.Health != "" {
+		k = append(k, stepHealth)
+	}
+	if s.Case != "" {
+		k = append(k, stepCase)
+	}
+	if s.Assert != nil {
+		k = append(k, stepAssert)
+	}
+	if s.Render != "" {
+		k = append(k, stepRender)
+	}
+	return k
+}
+
+func (s Step) validate() error {
+	k := s.kind()
+	if len(k) != 1 {
+		return fmt.Errorf("exactly one of load, wait, fault, health, case, assert and render is required, got %v", k)
+	}
+	switch k[0] {
+	case stepLoad:
+		if s.Load != loadStart && s.Load != loadStop {
+			return fmt.Errorf("load should be %s or %s", loadStart, loadStop)
+		}
+	case stepWait:
+		if _, err := time.ParseDuration(s.Wait); err != nil {
+			return err
+		}
+	case stepHealth:
+		if _, err := time.ParseDuration(s.Health); err != nil {
+			return err
+		}
+	case stepFault:
+		if _, ok := operator.GetOTypeByValue(s.Fault); !ok {
+			return fmt.Errorf("unknown fault: %s", s.Fault)
+		}
+		if s.Target == "" {
+			return fmt.Errorf("fault %s needs a target", s.Fault)
+		}
+	case stepAssert:
+		if s.Assert.SQL == "" {
+			return fmt.Errorf("assert needs the sql")
+		}
+	}
+	return nil
+}
+
+func (s Step) String() string {
+	k := s.kind()[0]
+	switch k {
+	case stepFault:
+		v := fmt.Sprintf("%s %s", s.Fault, s.Target)
+		if s.Per != "" {
+			v += fmt.Sprintf(" per %s", s.Per)
+		}
+		return fmt.Sprintf("%s: %s", k, v)
+	case stepAssert:
+		return fmt.Sprintf("%s: %s", k, s.Assert.SQL)
+	case stepLoad:
+		return fmt.Sprintf("%s: %s", k, s.Load)
+	case stepWait:
+		return fmt.Sprintf("%s: %s", k, s.Wait)
+	case stepHealth:
+		return fmt.Sprintf("%s: %s", k, s.Health)
+	case stepCase:
+		return fmt.Sprintf("%s: %s", k, s.Case)
+	default:
+		return fmt.Sprintf("%s: %s", k, s.Render)
+	}
+}
+
+// RunScenario runs the steps in order and stops at the first failed step, tree is the whole catalog for the case steps.
+func (j *Job) RunScenario(sc *Scenario, tree *widgets.Tree) {
+	j.report.Name = sc.Name
+	ctx, cancel := context.WithCancel(context.Background())
+	shellCtx, shellCancel := context.WithCancel(context.Background())
+	go ssh.S.ShellListener(shellCtx)
+	defer func() {
+		if Ld.running() {
+			j.stopLoad()
+		}
+		cancel()
+		waitAftercare()
+		shellCancel()
+		ssh.S.Close()
+		j.complete(true)
+	}()
+
+	if err := resetDB(); err != nil {
+		j.ErrC <- err
+		return
+	}
+	if err := j.createResult(sc.Name); err != nil {
+		j.ErrC <- err
+		return
+	}
+	j.startAt = time.Now()
+	j.begin = j.startAt
+	for i, s := range sc.Steps {
+		log.Logger.Infof("[scenario] [%d/%d] %s", i+1, len(sc.Steps), s)
+		failed := j.Failed()
+		err := j.runStep(ctx, tree, i+1, s)
+		if err == nil && j.Failed() > failed {
+			err = fmt.Errorf("%d case(s) failed", j.Failed()-failed)
+		}
+		if err != nil {
+			j.ErrC <- fmt.Errorf("[scenario] step %d %s failed: %w", i+1, s, err)
+			// the logs of a failed scenario are the most wanted.
+			j.checkLogs()
+			j.collectLogs()
+			return
+		}
+	}
+	j.checkLogs()
+	j.collectLogs()
+	if err := ssh.S.AfterCareShellLog(j.resultPath); err != nil {
+		j.ErrC <- err
+	}
+}
+
+func (j *Job) runStep(ctx context.Context, tree *widgets.Tree, n int, s Step) error {
+	switch s.kind()[0] {
+	case stepLoad:
+		ws, err := s.workloads()
+		if err != nil {
+			return err
+		}
+		if s.Load == loadStop {
+			if Ld.running() {
+				j.stopLoad(ws...)
+			}
+			return nil
+		}
+		return j.startLoad(ws...)
+	case stepWait:
+		d, _ := time.ParseDuration(s.Wait)
+		select {
+		case <-ctx.Done():
+		case <-time.After(d):
+		}
+		return nil
+	case stepFault:
+		return j.runFault(ctx, s)
+	case stepHealth:
+		d, _ := time.ParseDuration(s.Health)
+		rc := j.report.beginStep(fmt.Sprintf("step_%d_%s", n, stepHealth), stepHealth)
+		err := j.waitHealthy(ctx, rc, time.Now(), d)
+		j.report.finishErr(rc, err)
+		return err
+	case stepCase:
+		selected, err := widget.Select(tree, s.Case)
+		if err != nil {
+			return err
+		}
+		j.selected = selected
+		for _, p := range j.phases() {
+			j.selected = p.selected
+			if err := j.execute(ctx, p.oType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case stepAssert:
+		rc := j.report.beginStep(fmt.Sprintf("step_%d_%s", n, stepAssert), stepAssert)
+		err := j.assertStep(n, s.Assert)
+		j.report.finishErr(rc, err)
+		return err
+	case stepRender:
+		return j.render(s.Render)
+	}
+	return nil
+}
+
+// runFault runs the fault through the same functions as the catalog, the targets are the selected nodes.
+func (j *Job) runFault(ctx context.Context, s Step) error {
+	o, _ := operator.GetOTypeByValue(s.Fault)
+	selected := widgets.NewTree()
+	selected.Title = s.Fault
+	var nodes []*widgets.TreeNode
+	switch o {
+	case operator.Disaster, operator.NetworkPartition:
+		nodes = append(nodes, &widgets.TreeNode{Value: widget.NewExample(s.Target, comp.TiKV, o)})
+	default:
+		if symbol := symbolTarget(s.Target); symbol != "" {
+			if s.Per != "" {
+				return fmt.Errorf("per does not apply to %s", s.Target)
+			}
+			cType, _ := comp.SymbolCType(symbol)
+			nodes = append(nodes, &widgets.TreeNode{Value: widget.NewExample(symbol, cType, o)})
+			break
+		}
+		cType, cs, err := j.resolveTarget(s.Target, s.Per)
+		if err != nil {
+			return err
+		}
+		for _, c := range cs {
+			nodes = append(nodes, &widgets.TreeNode{Value: widget.NewExample(net.JoinHostPort(c.Host, c.Port), cType, o)})
+		}
+	}
+	selected.SetNodes(nodes)
+	j.selected = selected
+	j.noRecover = s.Recover != nil && !*s.Recover
+	defer func() {
+		j.noRecover = false
+	}()
+	return j.execute(ctx, o)
+}
+
+// symbolTarget returns the symbolic target such as pd-leader, tikv/random-tikv:zone=z1 or pd/leader, which is resolved when the fault runs.
+func symbolTarget(target string) string {
+	if target == "pd/leader" {
+		return comp.PDLeader
+	}
+	if i := strings.Index(target, "/"); i != -1 && !comp.IsSymbol(target) {
+		target = target[i+1:]
+	}
+	if comp.IsSymbol(target) {
+		return target
+	}
+	return ""
+}
+
+// resolveTarget parses tikv or tikv/127.0.0.1:20160, per picks one component for each value of the label key.
+func (j *Job) resolveTarget(target, per string) (comp.CType, []comp.Component, error) {
+	name, addr, _ := strings.Cut(target, "/")
+	cType, ok := cTypeByName[name]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown component: %s", name)
+	}
+	var cs []comp.Component
+	picked := make(map[string]bool)
+	for _, c := range j.components[cType] {
+		switch {
+		case addr == "":
+		case addr != net.JoinHostPort(c.Host, comp.CleanLeaderFlag(c.Port)):
+			continue
+		}
+		if per != "" {
+			v, ok := c.Labels[per]
+			if !ok || picked[v] {
+				continue
+			}
+			picked[v] = true
+		}
+		cs = append(cs, c)
+	}
+	if len(cs) == 0 {
+		return 0, nil, fmt.Errorf("target %s matches nothing", target)
+	}
+	return cType, cs, nil
+}
+
+func (j *Job) assertStep(n int, a *Assert) error {
+	rs, err := mysql.M.ExecuteScript(a.SQL, mysql.M.User, mysql.M.Password)
+	if err != nil {
+		return err
+	}
+	output := mysql.Render(rs)
+	j.writeResultFile(fmt.Sprintf("step_%d_%s", n, stepAssert), 1, 0, output)
+	if len(rs) == 0 {
+		return fmt.Errorf("no statement in %s", a.SQL)
+	}
+	last := rs[len(rs)-1]
+	switch {
+	case a.Error != 0:
+		if last.ErrCode != a.Error {
+			return fmt.Errorf("expected error %d, got %d %s", a.Error, last.ErrCode, last.ErrMsg)
+		}
+	case last.Failed():
+		return fmt.Errorf("ERROR %d: %s", last.ErrCode, last.ErrMsg)
+	}
+	if a.Rows != nil && !reflect.DeepEqual(a.Rows, last.Rows) && !(len(a.Rows) == 0 && len(last.Rows) == 0) {
+		return fmt.Errorf("expected rows %v, got %v", a.Rows, last.Rows)
+	}
+	if a.Result != "" {
+		expected, err := os.ReadFile(a.Result)
+		if err != nil {
+			return err
+		}
+		diff, err := mysql.Compare(expected, output)
+		if err != nil {
+			return err
+		}
+		if len(diff) != 0 {
+			j.writeResultFile(fmt.Sprintf("step_%d_%s.diff", n, stepAssert), 1, 0, diff)
+			return fmt.Errorf("mismatch %s", a.Result)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/pelletier/go-toml"
+	"pictorial/log"
+	"pictorial/server/job"
+	"pictorial/widget"
+)
+
+const ScenarioCommand = "scenario"
+
+// Scenario runs the steps of a scenario file without the termui interface,
+// e.g. tipoc scenario -c config.toml -f ha.yaml.
+func Scenario(args []string) int {
+
+	log.New(logName)
+
+	var cfgPath, scenarioPath string
+	fs := flag.NewFlagSet(ScenarioCommand, flag.ExitOnError)
+	fs.StringVar(&cfgPath, "c", defaultCfg, "config file")
+	fs.StringVar(&scenarioPath, "f", "", "scenario file, yaml or toml")
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(err)
+		return exitFail
+	}
+
+	go printLog()
+
+	if scenarioPath == "" {
+		log.Logger.Error("-f must not be empty")
+		return finish(exitFail)
+	}
+	sc, err := job.LoadScenario(scenarioPath)
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	cfg, err := toml.LoadFile(cfgPath)
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	if err := initConfig(cfg); err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	tree, err := widget.NewTree()
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+	w := widget.Widget{
+		T: tree,
+		S: widgets.NewTree(),
+	}
+	examples, err := w.WalkTreeScript()
+	if err != nil {
+		log.Logger.Error(err)
+		return finish(exitFail)
+	}
+
+	j := job.New(examples, w.S)
+	go j.RunScenario(sc, tree)
+	return wait(&j, 0)
+}
@@ -59,8 +59,9 @@ func New() {
 	s.w.T = tree
 	s.w.S = widget.NewSelected()
 	s.w.L = widget.NewLoad()
+	s.w.LP = widget.NewLoadPlot()
 	s.w.P = widget.NewProcessBar()
-	ui.Render(s.w.T, s.w.S, s.w.O, s.w.L, s.w.P)
+	ui.Render(s.w.T, s.w.S, s.w.O, s.w.L, s.w.LP, s.w.P)
 
 	previousKey := ""
 	for {
@@ -134,6 +135,7 @@ func (s *Server) run() error {
 	}
 
 	j := job.New(examples, s.w.S)
+	s.w.ResetLoadPlot()
 	go j.Run()
 
 	ue := ui.PollEvents()
@@ -149,6 +151,8 @@ func (s *Server) run() error {
 			s.w.RefreshProcessBar(idx)
 		case ldText := <-j.Channel.LdC:
 			s.w.PrintLoad(ldText)
+		case sample := <-j.Channel.SampleC:
+			s.w.PlotLoad(sample)
 		case <-j.Channel.CompleteC:
 			widget.CleanTree(s.w.S)
 			return fmt.Errorf(job.CompleteSignal)
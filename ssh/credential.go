@@ -0,0 +1,124 @@
+package ssh
+
+import (
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
+	"net"
+	"os"
+	"pictorial/log"
+	"sync"
+)
+
+const defaultSshPort = "22"
+
+// Credential is how to log in a host, the key, the keys of ssh-agent and the password are tried in order.
+type Credential struct {
+	User     string
+	Port     string
+	KeyPath  string
+	Agent    bool
+	Password string
+}
+
+// credential is the default credential, the user and the ssh port of the host in the topology fill the empty ones,
+// then the non-empty fields of the host in Hosts override it.
+func (s *SSH) credential(host string) Credential {
+	c := Credential{
+		User:     s.User,
+		Port:     s.SshPort,
+		KeyPath:  s.KeyPath,
+		Agent:    s.Agent,
+		Password: s.Password,
+	}
+	if s.Topology != nil {
+		if c.User == "" {
+			c.User = s.Topology.User
+		}
+		if port, ok := s.Topology.sshPort(host); ok && c.Port == "" {
+			c.Port = port
+		}
+	}
+	if h, ok := s.Hosts[host]; ok {
+		if h.User != "" {
+			c.User = h.User
+		}
+		if h.Port != "" {
+			c.Port = h.Port
+		}
+		if h.KeyPath != "" {
+			c.KeyPath = h.KeyPath
+		}
+		if h.Agent {
+			c.Agent = true
+		}
+		if h.Password != "" {
+			c.Password = h.Password
+		}
+	}
+	if c.Port == "" {
+		c.Port = defaultSshPort
+	}
+	return c
+}
+
+// authMethods are the usable methods of the credential, a key which can not be read is skipped with a warning.
+func (c Credential) authMethods(host string) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if c.KeyPath != "" {
+		signer, err := parsePrivateKey(c.KeyPath)
+		if err != nil {
+			log.Logger.Warnf("[%s] parse private key %s failed: %s", host, c.KeyPath, err.Error())
+		} else {
+			methods = append(methods, ssh.PublicKeys(signer))
+		}
+	}
+	if c.Agent {
+		a, err := sshAgent()
+		if err != nil {
+			log.Logger.Warnf("[%s] ssh-agent: %s", host, err.Error())
+		} else {
+			methods = append(methods, ssh.PublicKeysCallback(a.Signers))
+		}
+	}
+	if c.Password != "" {
+		methods = append(methods, ssh.Password(c.Password))
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no credential to ssh %s@%s, set ssh.key, ssh.agent or ssh.password", c.User, host)
+	}
+	return methods, nil
+}
+
+func parsePrivateKey(path string) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(b)
+}
+
+var (
+	agentMu     sync.Mutex
+	agentClient agent.ExtendedAgent
+)
+
+// sshAgent connects SSH_AUTH_SOCK once and keeps the connection for the later logins.
+func sshAgent() (agent.ExtendedAgent, error) {
+	agentMu.Lock()
+	defer agentMu.Unlock()
+	if agentClient != nil {
+		return agentClient, nil
+	}
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+	agentClient = agent.NewClient(conn)
+	return agentClient, nil
+}
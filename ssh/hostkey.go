@@ -0,0 +1,199 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"net"
+	"os"
+	"path/filepath"
+	"pictorial/log"
+	"sync"
+)
+
+// the ways to verify the host keys, ssh.host_key of the config.
+const (
+	// HostKeyStrict rejects the hosts which are not in known_hosts.
+	HostKeyStrict = "strict"
+	// HostKeyTOFU trusts a host on the first login and records its key to known_hosts, like StrictHostKeyChecking=accept-new.
+	HostKeyTOFU = "tofu"
+	// HostKeyInsecure does not verify the host keys.
+	HostKeyInsecure = "insecure"
+)
+
+const knownHostsFile = "known_hosts"
+
+// hostKeys verifies the host keys against the known_hosts files, the keys trusted on the first login are appended to record.
+type hostKeys struct {
+	mu       sync.Mutex
+	mode     string
+	files    []string
+	record   string
+	callback ssh.HostKeyCallback
+}
+
+var knownHosts *hostKeys
+
+// InitHostKeys loads KnownHosts, ~/.ssh/known_hosts by default, and the known_hosts kept in the ssh dir of the tiup
+// cluster if there is one.
+func (s *SSH) InitHostKeys() error {
+	mode := s.HostKey
+	if mode == "" {
+		mode = HostKeyTOFU
+	}
+	switch mode {
+	case HostKeyStrict, HostKeyTOFU, HostKeyInsecure:
+	default:
+		return fmt.Errorf("unknown ssh host key mode %s, it is %s, %s or %s", mode, HostKeyStrict, HostKeyTOFU, HostKeyInsecure)
+	}
+	record := s.KnownHosts
+	if record == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		record = filepath.Join(home, ".ssh", knownHostsFile)
+	}
+	h := &hostKeys{mode: mode, record: record, files: []string{record}}
+	for _, dir := range s.clusterSSHDirs() {
+		f := filepath.Join(dir, knownHostsFile)
+		if _, err := os.Stat(f); err == nil && f != record {
+			h.files = append(h.files, f)
+		}
+	}
+	if mode == HostKeyInsecure {
+		log.Logger.Warn("the ssh host keys are not verified")
+	}
+	knownHosts = h
+	return nil
+}
+
+// clusterSSHDirs are the ssh dirs of the cluster, next to meta.yaml of the topology and in the tiup storage.
+func (s *SSH) clusterSSHDirs() []string {
+	var dirs []string
+	if s.Topology != nil && s.Topology.keyPath != "" {
+		dirs = append(dirs, filepath.Dir(s.Topology.keyPath))
+	}
+	if root, err := s.WhereTiup(); err == nil {
+		dirs = append(dirs, filepath.Dir(privateKeyPath(root, s.Cluster.Name)))
+	}
+	return dirs
+}
+
+// load parses the known_hosts files which exist, it is called again after a key is recorded.
+func (h *hostKeys) load() (ssh.HostKeyCallback, error) {
+	if h.callback != nil {
+		return h.callback, nil
+	}
+	var files []string
+	for _, f := range h.files {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		// no host is known yet.
+		h.callback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return &knownhosts.KeyError{}
+		}
+		return h.callback, nil
+	}
+	cb, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("load %v failed: %w", files, err)
+	}
+	h.callback = cb
+	return cb, nil
+}
+
+func (h *hostKeys) verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if h.mode == HostKeyInsecure {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cb, err := h.load()
+	if err != nil {
+		return err
+	}
+	err = cb(hostname, remote, key)
+	var ke *knownhosts.KeyError
+	if err == nil || !errors.As(err, &ke) {
+		return err
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	if len(ke.Want) != 0 {
+		return fmt.Errorf("host key of %s changed, got %s %s, check it and the %s at %s:%d",
+			hostname, key.Type(), fingerprint, knownHostsFile, ke.Want[0].Filename, ke.Want[0].Line)
+	}
+	if h.mode == HostKeyStrict {
+		return fmt.Errorf("host key of %s is unknown, %s %s, add it to %s or set ssh.host_key = \"%s\"",
+			hostname, key.Type(), fingerprint, h.record, HostKeyTOFU)
+	}
+	if err := h.add(hostname, key); err != nil {
+		return err
+	}
+	log.Logger.Infof("[%s] trust the host key %s %s, recorded to %s", hostname, key.Type(), fingerprint, h.record)
+	return nil
+}
+
+func (h *hostKeys) add(hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(h.record), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return err
+	}
+	h.callback = nil
+	return nil
+}
+
+// algorithms are the types of the known keys of the host, so the server offers the key which is verified.
+// They are empty for an unknown host and any key is offered.
+func (h *hostKeys) algorithms(addr string) []string {
+	if h.mode == HostKeyInsecure {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cb, err := h.load()
+	if err != nil {
+		return nil
+	}
+	// a key which is never known asks known_hosts for the keys of the host.
+	_, pub, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil
+	}
+	probe, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil
+	}
+	var ke *knownhosts.KeyError
+	if !errors.As(cb(addr, &net.TCPAddr{}, probe), &ke) {
+		return nil
+	}
+	var algos []string
+	seen := make(map[string]bool)
+	for _, k := range ke.Want {
+		types := []string{k.Key.Type()}
+		if k.Key.Type() == ssh.KeyAlgoRSA {
+			types = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, t := range types {
+			if !seen[t] {
+				seen[t] = true
+				algos = append(algos, t)
+			}
+		}
+	}
+	return algos
+}
@@ -0,0 +1,136 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logTimeFormat is the time of the log lines of tidb, tikv, pd and tiflash in the format of date, e.g.
+// [2023/07/06 23:57:09.123 +08:00], they are in the time zone of the host so the window is formatted there.
+const logTimeFormat = "+%Y/%m/%d %H:%M:%S"
+
+// sliceLog keeps the lines in [from, to], the lines without a time, e.g. a stack, follow the line before them.
+const sliceLog = `/^\[[0-9][0-9][0-9][0-9]\/[0-9][0-9]\/[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]/ ` +
+	`{ t = substr($0, 2, 19); keep = (t >= from && t <= to) } keep`
+
+// matchLog counts the lines matching the patterns TIPOC_P1..TIPOC_P<k> and the lines by the message, the 4th field
+// of a log line, e.g. ["encountered error"]. The first n lines of each pattern are printed as well.
+const matchLog = `BEGIN { OFS = "\t"; k = ENVIRON["TIPOC_K"]; n = ENVIRON["TIPOC_N"]; ` +
+	`for (i = 1; i <= k; i++) p[i] = ENVIRON["TIPOC_P" i] } ` +
+	`{ for (i = 1; i <= k; i++) if ($0 ~ p[i]) { c[i]++; if (c[i] <= n) print "L", i, $0; ` +
+	`split($0, a, "] \\["); m = a[4]; sub(/\].*$/, "", m); gsub(/"/, "", m); cnt[i, m]++ } } ` +
+	`END { for (i = 1; i <= k; i++) print "C", i, c[i] + 0; ` +
+	`for (x in cnt) { split(x, y, SUBSEP); print "M", y[1], cnt[x], y[2] } }`
+
+// logWindow sets $from and $to of the shell to the window in the time zone of the host and defines slice, which
+// prints the lines of a log in the window. The stderr logs have no time and are printed whole.
+func logWindow(from, to time.Time) string {
+	return fmt.Sprintf("from=$(date -d @%d '%s'); to=$(date -d @%d '%s'); "+
+		"slice() { case \"$1\" in *stderr*) cat \"$1\";; *) awk -v from=\"$from\" -v to=\"$to\" '%s' \"$1\";; esac; }; ",
+		from.Unix(), logTimeFormat, to.Unix(), logTimeFormat, sliceLog)
+}
+
+// eachLog runs body for each log $f of the dir written since from.
+func eachLog(dir string, from time.Time, body string) string {
+	return fmt.Sprintf("find %s -maxdepth 1 -type f -name '*.log*' ! -name '*.gz' -newermt @%d 2>/dev/null | "+
+		"while IFS= read -r f; do %s; done", quote(dir), from.Unix(), body)
+}
+
+// CollectLogs slices the logs of the dirs to the window on the host and downloads them as a tar.gz to local,
+// the dirs are keyed by the name of the dir in the bundle, e.g. tikv-20160. The kernel messages and the journal
+// of the window are added with system.
+func (s *SSH) CollectLogs(host string, dirs map[string]string, from, to time.Time, system bool, local string) (*TransferResult, error) {
+	tmp := fmt.Sprintf("/tmp/tipoc-logs-%d", time.Now().UnixNano())
+	var b strings.Builder
+	b.WriteString(logWindow(from, to))
+	fmt.Fprintf(&b, "mkdir -p %s; ", tmp)
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out := quote(path.Join(tmp, name))
+		fmt.Fprintf(&b, "mkdir -p %s; ", out)
+		b.WriteString(eachLog(dirs[name], from,
+			fmt.Sprintf("o=%s/$(basename \"$f\"); slice \"$f\" > \"$o\"; [ -s \"$o\" ] || rm -f \"$o\"", out)))
+		b.WriteString("; ")
+	}
+	if system {
+		fmt.Fprintf(&b, "sudo dmesg -T > %s/dmesg.log 2>&1; ", tmp)
+		fmt.Fprintf(&b, "sudo journalctl --since @%d --until @%d --no-pager > %s/journal.log 2>&1; ",
+			from.Unix(), to.Unix(), tmp)
+	}
+	fmt.Fprintf(&b, "tar czf %s.tar.gz -C %s . && rm -rf %s", tmp, tmp, tmp)
+	if _, err := s.RunSSH(host, b.String()); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, _ = s.Remove(host, tmp+".tar.gz")
+	}()
+	return s.Download(host, tmp+".tar.gz", local)
+}
+
+// LogMatch is the lines of the logs in a window which match a pattern.
+type LogMatch struct {
+	Count int
+	// Lines are the first matched lines.
+	Lines []string
+	// Messages are the counts of the matched lines by the message of the log, e.g. encountered error.
+	Messages map[string]int
+}
+
+// ScanLogs matches the lines of the logs of the dir in the window against the extended regular expressions in one
+// pass on the host, the first n lines of each pattern are kept.
+func (s *SSH) ScanLogs(host, dir string, from, to time.Time, patterns []string, n int) ([]LogMatch, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	var env strings.Builder
+	fmt.Fprintf(&env, "TIPOC_K=%d TIPOC_N=%d", len(patterns), n)
+	for i, p := range patterns {
+		fmt.Fprintf(&env, " TIPOC_P%d=%s", i+1, quote(p))
+	}
+	c := logWindow(from, to) + "{ " + eachLog(dir, from, "slice \"$f\"") + "; } | " +
+		fmt.Sprintf("%s awk '%s'", env.String(), matchLog)
+	o, err := s.RunSSH(host, c)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]LogMatch, len(patterns))
+	for i := range matches {
+		matches[i].Messages = make(map[string]int)
+	}
+	sc := bufio.NewScanner(strings.NewReader(string(o)))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), "\t", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		i, err := strconv.Atoi(fields[1])
+		if err != nil || i < 1 || i > len(patterns) {
+			continue
+		}
+		m := &matches[i-1]
+		switch fields[0] {
+		case "L":
+			m.Lines = append(m.Lines, strings.Join(fields[2:], "\t"))
+		case "C":
+			m.Count, _ = strconv.Atoi(fields[2])
+		case "M":
+			cnt, _ := strconv.Atoi(fields[2])
+			msg := ""
+			if len(fields) == 4 {
+				msg = fields[3]
+			}
+			m.Messages[msg] = cnt
+		}
+	}
+	return matches, sc.Err()
+}
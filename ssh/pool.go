@@ -0,0 +1,203 @@
+package ssh
+
+import (
+	"fmt"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// an idle connection is probed every keepAliveInterval, the one which does not answer in keepAliveTimeout is
+// closed and the next command dials the host again.
+const (
+	keepAliveInterval = 15 * time.Second
+	keepAliveTimeout  = 10 * time.Second
+)
+
+// defaultConcurrency is the hosts which run a command at once by Parallel if Concurrency is not set.
+const defaultConcurrency = 16
+
+// client is a pooled connection, the sessions of the commands to the same host share it.
+type client struct {
+	*ssh.Client
+	key  string
+	done chan struct{}
+	// mu guards sftp, which is opened by the first transfer.
+	mu   sync.Mutex
+	sftp *sftp.Client
+}
+
+type pool struct {
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+var clients = &pool{clients: make(map[string]*client)}
+
+// client is the pooled connection of the host, it is dialed if there is none or the former one is broken.
+func (s *SSH) client(host string) (*client, error) {
+	c := s.credential(host)
+	key := fmt.Sprintf("%s@%s", c.User, net.JoinHostPort(host, c.Port))
+	clients.mu.Lock()
+	pc, ok := clients.clients[key]
+	clients.mu.Unlock()
+	if ok {
+		return pc, nil
+	}
+	sc, err := s.NewSshClient(host)
+	if err != nil {
+		return nil, err
+	}
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+	// another command to the host may dial it at the same time, the first connection is kept.
+	if pc, ok := clients.clients[key]; ok {
+		_ = sc.Close()
+		return pc, nil
+	}
+	pc = &client{Client: sc, key: key, done: make(chan struct{})}
+	clients.clients[key] = pc
+	go pc.keepAlive()
+	go func() {
+		_ = sc.Wait()
+		clients.drop(pc)
+	}()
+	return pc, nil
+}
+
+// session opens a session on the pooled connection of the host, a broken connection is dialed again once.
+func (s *SSH) session(host string) (*ssh.Session, error) {
+	pc, err := s.client(host)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := pc.NewSession()
+	if err == nil {
+		return ss, nil
+	}
+	clients.drop(pc)
+	if pc, err = s.client(host); err != nil {
+		return nil, err
+	}
+	return pc.NewSession()
+}
+
+func (c *client) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		errC := make(chan error, 1)
+		go func() {
+			_, _, err := c.SendRequest("keepalive@openssh.com", true, nil)
+			errC <- err
+		}()
+		select {
+		case <-c.done:
+			return
+		case err := <-errC:
+			if err != nil {
+				clients.drop(c)
+				return
+			}
+		case <-time.After(keepAliveTimeout):
+			clients.drop(c)
+			return
+		}
+	}
+}
+
+// drop closes the connection and removes it from the pool if it is still the pooled one of the host.
+func (p *pool) drop(c *client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients[c.key] == c {
+		delete(p.clients, c.key)
+	}
+	select {
+	case <-c.done:
+		return
+	default:
+		close(c.done)
+	}
+	c.mu.Lock()
+	if c.sftp != nil {
+		_ = c.sftp.Close()
+	}
+	c.mu.Unlock()
+	_ = c.Close()
+}
+
+// Close closes the pooled connections of all the hosts.
+func (s *SSH) Close() {
+	clients.mu.Lock()
+	cs := make([]*client, 0, len(clients.clients))
+	for _, c := range clients.clients {
+		cs = append(cs, c)
+	}
+	clients.mu.Unlock()
+	for _, c := range cs {
+		clients.drop(c)
+	}
+}
+
+// Result is the output of a host of Parallel.
+type Result struct {
+	Host   string
+	Output []byte
+	Err    error
+}
+
+// Parallel calls fn for the hosts at the same time, at most Concurrency of them at once,
+// the results are in the order of the hosts.
+func (s *SSH) Parallel(hosts []string, fn func(host string) ([]byte, error)) []Result {
+	limit := s.Concurrency
+	if limit <= 0 {
+		limit = defaultConcurrency
+	}
+	results := make([]Result, len(hosts))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, h string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			o, err := fn(h)
+			results[i] = Result{Host: h, Output: o, Err: err}
+		}(i, h)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunParallel runs the command on the hosts at the same time.
+func (s *SSH) RunParallel(hosts []string, c string) []Result {
+	return s.Parallel(hosts, func(host string) ([]byte, error) {
+		return s.RunSSH(host, c)
+	})
+}
+
+// Failed is the errors of the failed hosts joined, nil if all of them succeed.
+func Failed(results []Result) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", r.Host, r.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(failed, "; "))
+}
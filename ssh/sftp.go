@@ -0,0 +1,312 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/sftp"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"pictorial/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often the progress of a long transfer is logged.
+const progressInterval = 5 * time.Second
+
+// TransferResult is what a transfer copied, every file is verified by sha256 on both sides.
+type TransferResult struct {
+	Files   int
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+func (r *TransferResult) String() string {
+	return fmt.Sprintf("%d file(s), %.1fMB in %.1fs", r.Files, float64(r.Bytes)/1024/1024, r.Elapsed.Seconds())
+}
+
+// sftp is the sftp client on the pooled connection of the host, it is opened once and shared by the transfers.
+func (s *SSH) sftp(host string) (*sftp.Client, error) {
+	pc, err := s.client(host)
+	if err != nil {
+		return nil, err
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.sftp == nil {
+		if pc.sftp, err = sftp.NewClient(pc.Client); err != nil {
+			return nil, fmt.Errorf("[%s] sftp: %w", host, err)
+		}
+	}
+	return pc.sftp, nil
+}
+
+// transfer is the state of an Upload or a Download, the sums are the sha256 of the remote files copied.
+type transfer struct {
+	host     string
+	name     string
+	result   TransferResult
+	mu       sync.Mutex
+	sums     map[string]string
+	loggedAt time.Time
+}
+
+func newTransfer(host, name string) *transfer {
+	return &transfer{host: host, name: name, sums: make(map[string]string), loggedAt: time.Now()}
+}
+
+// add counts the bytes written and logs the progress at most every progressInterval.
+func (t *transfer) add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Bytes += int64(n)
+	if time.Since(t.loggedAt) >= progressInterval {
+		t.loggedAt = time.Now()
+		log.Logger.Infof("[%s] %s: %d file(s), %.1fMB", t.host, t.name, t.result.Files, float64(t.result.Bytes)/1024/1024)
+	}
+}
+
+// copy copies the file and keeps the sha256 of the data for the remote path.
+func (t *transfer) copy(dst io.Writer, src io.Reader, remote string) error {
+	h := sha256.New()
+	if _, err := io.Copy(&progressWriter{w: io.MultiWriter(dst, h), t: t}, src); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.result.Files++
+	t.sums[remote] = sum(h)
+	t.mu.Unlock()
+	return nil
+}
+
+type progressWriter struct {
+	w io.Writer
+	t *transfer
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.t.add(n)
+	return n, err
+}
+
+func sum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Upload copies the local files or dirs matched by the glob to the host, a single one is put into remote if remote
+// is a dir, like scp -r, and several ones need remote to be a dir.
+func (s *SSH) Upload(host, local, remote string) (*TransferResult, error) {
+	sc, err := s.sftp(host)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(local)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("upload %s: no such file", local)
+	}
+	fi, err := sc.Stat(remote)
+	isDir := err == nil && fi.IsDir()
+	if len(matches) > 1 && !isDir {
+		return nil, fmt.Errorf("upload %s: %s:%s is not a dir", local, host, remote)
+	}
+	t := newTransfer(host, fmt.Sprintf("upload %s -> %s", local, remote))
+	s.LogC <- formatCommand(t.name, host)
+	start := time.Now()
+	var roots []string
+	for _, m := range matches {
+		root := remote
+		if isDir {
+			root = path.Join(remote, filepath.Base(m))
+		}
+		roots = append(roots, root)
+		if err := t.upload(sc, m, root); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.verify(host, t, roots); err != nil {
+		return nil, err
+	}
+	t.result.Elapsed = time.Since(start)
+	log.Logger.Infof("[%s] %s, %s", host, t.name, t.result.String())
+	return &t.result, nil
+}
+
+func (t *transfer) upload(sc *sftp.Client, local, remote string) error {
+	return filepath.Walk(local, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(local, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(remote, filepath.ToSlash(rel))
+		if fi.IsDir() {
+			return sc.MkdirAll(target)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := sc.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+		if err != nil {
+			return fmt.Errorf("upload %s failed: %w", target, err)
+		}
+		defer dst.Close()
+		if err := t.copy(dst, src, target); err != nil {
+			return fmt.Errorf("upload %s failed: %w", target, err)
+		}
+		return dst.Chmod(fi.Mode().Perm())
+	})
+}
+
+// Download copies the remote files or dirs matched by the glob from the host, a single one is put into local if
+// local is a dir, like scp -r, and several ones need local to be a dir.
+func (s *SSH) Download(host, remote, local string) (*TransferResult, error) {
+	sc, err := s.sftp(host)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := sc.Glob(remote)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("download %s:%s: no such file", host, remote)
+	}
+	fi, err := os.Stat(local)
+	isDir := err == nil && fi.IsDir()
+	if len(matches) > 1 && !isDir {
+		return nil, fmt.Errorf("download %s:%s: %s is not a dir", host, remote, local)
+	}
+	t := newTransfer(host, fmt.Sprintf("download %s -> %s", remote, local))
+	s.LogC <- formatCommand(t.name, host)
+	start := time.Now()
+	for _, m := range matches {
+		root := local
+		if isDir {
+			root = filepath.Join(local, path.Base(m))
+		}
+		if err := t.download(sc, m, root); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.verify(host, t, matches); err != nil {
+		return nil, err
+	}
+	t.result.Elapsed = time.Since(start)
+	log.Logger.Infof("[%s] %s, %s", host, t.name, t.result.String())
+	return &t.result, nil
+}
+
+func (t *transfer) download(sc *sftp.Client, remote, local string) error {
+	w := sc.Walk(remote)
+	for w.Step() {
+		if w.Err() != nil {
+			return w.Err()
+		}
+		rel, err := filepath.Rel(remote, w.Path())
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(local, rel)
+		if w.Stat().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if !w.Stat().Mode().IsRegular() {
+			continue
+		}
+		if err := t.downloadFile(sc, w.Path(), target, w.Stat().Mode()); err != nil {
+			return fmt.Errorf("download %s failed: %w", w.Path(), err)
+		}
+	}
+	return nil
+}
+
+func (t *transfer) downloadFile(sc *sftp.Client, remote, local string, mode os.FileMode) error {
+	src, err := sc.Open(remote)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(local, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	return t.copy(dst, src, remote)
+}
+
+// verify compares the sha256 of the copied data with sha256sum of the files on the host.
+func (s *SSH) verify(host string, t *transfer, roots []string) error {
+	if len(t.sums) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(roots))
+	for i, r := range roots {
+		quoted[i] = quote(r)
+	}
+	c := fmt.Sprintf("find %s -type f -exec sha256sum {} +", strings.Join(quoted, " "))
+	ss, err := s.session(host)
+	if err != nil {
+		return err
+	}
+	defer ss.Close()
+	o, err := ss.Output(c)
+	if err != nil {
+		return fmt.Errorf("[%s] verify %s failed: %w", host, t.name, err)
+	}
+	remote := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(string(o)))
+	for sc.Scan() {
+		// <sum>  <path>
+		fields := strings.SplitN(sc.Text(), "  ", 2)
+		if len(fields) == 2 {
+			remote[fields[1]] = fields[0]
+		}
+	}
+	for p, want := range t.sums {
+		if got, ok := remote[p]; !ok || got != want {
+			return fmt.Errorf("[%s] %s: sha256 of %s mismatch, %s != %s", host, t.name, p, want, got)
+		}
+	}
+	return nil
+}
+
+// quote is the path as one word of the shell.
+func quote(p string) string {
+	return "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
+
+// copyFile copies a local file, e.g. shell.log into the result dir.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
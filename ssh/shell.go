@@ -3,6 +3,7 @@ package ssh
 import (
 	"bufio"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,16 +12,6 @@ func (s *SSH) DirWalk(host, target string) ([]byte, error) {
 	return s.RunSSH(host, c)
 }
 
-func (s *SSH) Transfer(t1, t2 string) ([]byte, error) {
-	c := fmt.Sprintf("scp -o StrictHostKeyChecking=no -i %s %s %s", s.sshKey.privateKey, t1, t2)
-	return s.RunLocal(c)
-}
-
-func (s *SSH) TransferR(t1, t2 string) ([]byte, error) {
-	c := fmt.Sprintf("scp -r -o StrictHostKeyChecking=no -i %s %s %s", s.sshKey.privateKey, t1, t2)
-	return s.RunLocal(c)
-}
-
 func (s *SSH) UnZip(host, obj, path string) ([]byte, error) {
 	c := fmt.Sprintf("unzip -o %s -d %s", obj, path)
 	return s.RunSSH(host, c)
@@ -46,8 +37,9 @@ func (s *SSH) YumInstall(host, repo string) ([]byte, error) {
 	return s.RunSSH(host, c)
 }
 
-func (s *SSH) GrepTailN(host, path string, cnt int) ([]byte, error) {
-	c := fmt.Sprintf("grep %s %s | tail -n %d", host, path, cnt)
+// GrepTailN is the last cnt lines of the log which match the extended regular expression, empty if none matches.
+func (s *SSH) GrepTailN(host, pattern, path string, cnt int) ([]byte, error) {
+	c := fmt.Sprintf("grep -E -- %s %s | tail -n %d", quote(pattern), quote(path), cnt)
 	return s.RunSSH(host, c)
 }
 
@@ -72,3 +64,69 @@ func (s *SSH) GetProcessIDByPs(host, psCmd string) ([]string, error) {
 	}
 	return id, nil
 }
+
+const iptablesComment = "-m comment --comment tipoc -j DROP"
+
+// IptablesChains are the chains dropping the traffic with a peer, each one is a rule added and removed on its own.
+var IptablesChains = []string{"INPUT", "OUTPUT"}
+
+// iptablesMatch matches the traffic from the peer in INPUT and to the peer in OUTPUT.
+func iptablesMatch(chain, peer string) string {
+	if chain == "INPUT" {
+		return fmt.Sprintf("-s %s", peer)
+	}
+	return fmt.Sprintf("-d %s", peer)
+}
+
+func (s *SSH) IptablesDrop(host, chain, peer string) ([]byte, error) {
+	c := fmt.Sprintf("sudo iptables -I %s %s %s", chain, iptablesMatch(chain, peer), iptablesComment)
+	return s.RunSSH(host, c)
+}
+
+func (s *SSH) IptablesRecover(host, chain, peer string) ([]byte, error) {
+	c := fmt.Sprintf("sudo iptables -D %s %s %s", chain, iptablesMatch(chain, peer), iptablesComment)
+	return s.RunSSH(host, c)
+}
+
+// ListeningPorts returns the tcp ports listened on the host.
+func (s *SSH) ListeningPorts(host string) ([]int, error) {
+	o, err := s.RunSSH(host, "ss -tln | awk 'NR>1 {print $4}'")
+	if err != nil {
+		return nil, err
+	}
+	var ports []int
+	sc := bufio.NewScanner(strings.NewReader(string(o)))
+	for sc.Scan() {
+		addr := sc.Text()
+		if port, err := strconv.Atoi(addr[strings.LastIndex(addr, ":")+1:]); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+func (s *SSH) NetDevice(host, peer string) (string, error) {
+	c := fmt.Sprintf("ip route get %s | grep -oP 'dev \\K\\S+'", peer)
+	o, err := s.RunSSH(host, c)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(o)), nil
+}
+
+// NetemAdd shapes the traffic to the peers only, the other traffic keeps the default bands of prio.
+func (s *SSH) NetemAdd(host, dev, netem string, peers []string) ([]byte, error) {
+	cmd := []string{
+		fmt.Sprintf("sudo tc qdisc replace dev %s root handle 1: prio bands 4", dev),
+		fmt.Sprintf("sudo tc qdisc replace dev %s parent 1:4 handle 40: netem %s", dev, netem),
+	}
+	for _, p := range peers {
+		cmd = append(cmd, fmt.Sprintf("sudo tc filter add dev %s protocol ip parent 1:0 prio 4 u32 match ip dst %s/32 flowid 1:4", dev, p))
+	}
+	return s.RunSSH(host, strings.Join(cmd, " && "))
+}
+
+func (s *SSH) NetemDel(host, dev string) ([]byte, error) {
+	c := fmt.Sprintf("sudo tc qdisc del dev %s root 2>/dev/null || true", dev)
+	return s.RunSSH(host, c)
+}
@@ -6,20 +6,37 @@ import (
 	"fmt"
 	"golang.org/x/crypto/ssh"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"pictorial/log"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
+// SSH runs the commands on the hosts, User, SshPort, KeyPath, Agent and Password are the default credential.
 type SSH struct {
 	User     string
 	Password string
 	SshPort  string
-	LogC     chan string
+	// KeyPath is the private key, the key kept by tiup for the cluster is used if it is empty.
+	KeyPath string
+	// Agent logs in by the keys of the ssh-agent at SSH_AUTH_SOCK.
+	Agent bool
+	// Hosts override the credential of some hosts, the empty fields are the defaults.
+	Hosts map[string]Credential
+	// Topology is read from meta.yaml or an inventory file instead of the tiup storage if it is set.
+	Topology *Topology
+	// HostKey is how the host keys are verified, strict, tofu or insecure.
+	HostKey string
+	// KnownHosts is the known_hosts file which the host keys are verified against and trusted keys are recorded to.
+	KnownHosts string
+	// Concurrency is the hosts which run a command at once by Parallel.
+	Concurrency int
+	LogC        chan string
 	Cluster
-	sshKey
 	Ctx context.Context
 }
 
@@ -27,51 +44,35 @@ type Cluster struct {
 	Name string
 }
 
-type sshKey struct {
-	publicKey  string
-	privateKey string
-}
-
 var S SSH
 
 const localhost = "localhost"
 
 func (s *SSH) NewSshClient(host string) (*ssh.Client, error) {
-	rs, err := s.ParsePrivateKey()
-	sshConfig := newSshConfig(s.User)
+	c := s.credential(host)
+	auth, err := c.authMethods(host)
 	if err != nil {
-		if s.Password != "" {
-			sshConfig.Auth = []ssh.AuthMethod{
-				ssh.Password(s.Password),
-			}
-			log.Logger.Warnf("parse privateKey: %s failed, err: %s, retry password: %s", s.sshKey.privateKey, err.Error(), s.Password)
-		} else {
-			return nil, fmt.Errorf("ssh failed, privateKey: %s, password: %s, please check", s.privateKey, s.Password)
-		}
-	} else {
-		sshConfig.Auth = []ssh.AuthMethod{ssh.PublicKeys(rs)}
+		return nil, err
 	}
-	addr := fmt.Sprintf("%s:%s", host, s.SshPort)
-	return ssh.Dial("tcp", addr, sshConfig)
-}
-
-func newSshConfig(user string) *ssh.ClientConfig {
-	return &ssh.ClientConfig{
-		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	if knownHosts == nil {
+		return nil, fmt.Errorf("the ssh host keys are not loaded")
+	}
+	addr := net.JoinHostPort(host, c.Port)
+	sshConfig := &ssh.ClientConfig{
+		User:              c.User,
+		Auth:              auth,
+		HostKeyCallback:   knownHosts.verify,
+		HostKeyAlgorithms: knownHosts.algorithms(addr),
 	}
+	return ssh.Dial("tcp", addr, sshConfig)
 }
 
 const failedMsg = "'%s' error: %w: %s, %s"
 const warnMsg = "'%s' warn: %w: %s, %s"
 
+// RunSSH runs the command in a session of the pooled connection of the host.
 func (s *SSH) RunSSH(h, c string) ([]byte, error) {
-	sc, err := s.NewSshClient(h)
-	if err != nil {
-		return nil, err
-	}
-	defer sc.Close()
-	ss, err := sc.NewSession()
+	ss, err := s.session(h)
 	if err != nil {
 		return nil, err
 	}
@@ -79,10 +80,9 @@ func (s *SSH) RunSSH(h, c string) ([]byte, error) {
 	var stdout, stderr bytes.Buffer
 	ss.Stdout = &stdout
 	ss.Stderr = &stderr
-	s.LogC <- formatCommand(c, h)
 	err = ss.Run(c)
-	s.LogC <- formatStdout(stdout)
-	s.LogC <- formatStderr(stderr)
+	// the command and its output are sent at once, so they are not interleaved with the ones of the other hosts.
+	s.LogC <- formatCommand(c, h) + formatStdout(stdout) + formatStderr(stderr)
 	if err != nil {
 		if _, ok := err.(*ssh.ExitError); ok {
 			return nil, fmt.Errorf(failedMsg, c, err, stdout.String(), stderr.String())
@@ -146,12 +146,7 @@ func (s *SSH) RunLocalWithArg(c string, arg []string) ([]byte, error) {
 
 func (s *SSH) RunSSHWithContext(ctx context.Context, host, c string) ([]byte, error) {
 
-	sc, err := s.NewSshClient(host)
-	if err != nil {
-		return nil, err
-	}
-	defer sc.Close()
-	ss, err := sc.NewSession()
+	ss, err := s.session(host)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +182,7 @@ func (s *SSH) RunSSHWithContext(ctx context.Context, host, c string) ([]byte, er
 	return stdout.Bytes(), nil
 }
 
+// RunLocalWithContext appends the stdout to fName if it is not empty, so a restarted command keeps the former output.
 func (s *SSH) RunLocalWithContext(ctx context.Context, c string, arg []string, fName string) ([]byte, error) {
 
 	cmd := exec.Command(c, arg...)
@@ -194,7 +190,7 @@ func (s *SSH) RunLocalWithContext(ctx context.Context, c string, arg []string, f
 	cmd.Stderr = &stderr
 
 	if fName != "" {
-		f, err := os.Create(fName)
+		f, err := os.OpenFile(fName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
 			return nil, err
 		}
@@ -208,16 +204,22 @@ func (s *SSH) RunLocalWithContext(ctx context.Context, c string, arg []string, f
 	s.LogC <- formatStdout(stdout)
 	s.LogC <- formatStderr(stderr)
 
-	errC := make(chan error)
+	// the command runs in its own process group, so the children of sh, e.g. sysbench, are killed with it.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf(failedMsg, c, err, stdout.String(), stderr.String())
+	}
+	errC := make(chan error, 1)
 	go func() {
-		errC <- cmd.Run()
+		errC <- cmd.Wait()
 	}()
 
 	select {
 	case <-ctx.Done():
-		if err := cmd.Process.Kill(); err != nil {
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
 			return nil, err
 		}
+		<-errC
 	case err := <-errC:
 		if err != nil {
 			if _, ok := err.(*ssh.ExitError); ok {
@@ -258,8 +260,7 @@ func (s *SSH) ShellListener(ctx context.Context) {
 }
 
 func (s *SSH) AfterCareShellLog(path string) error {
-	c := fmt.Sprintf("scp -o StrictHostKeyChecking=no -i %s %s %s", s.sshKey.privateKey, shellLog, path)
-	if _, err := s.RunLocalWithoutListener(c); err != nil {
+	if err := copyFile(shellLog, filepath.Join(path, shellLog)); err != nil {
 		return err
 	}
 	return os.Remove(shellLog)
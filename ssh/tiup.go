@@ -2,21 +2,37 @@ package ssh
 
 import (
 	"fmt"
-	"golang.org/x/crypto/ssh"
-	"io/ioutil"
+	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"pictorial/log"
 	"strings"
 )
 
+// AddSSHKey finds the key if ssh.key is not set, it is the one next to meta.yaml of the topology
+// or the one in the tiup storage. No key is fine if the agent or the password logs in.
 func (s *SSH) AddSSHKey() error {
+	if s.KeyPath != "" {
+		if _, err := os.Stat(s.KeyPath); err != nil {
+			return fmt.Errorf("ssh key %s: %w", s.KeyPath, err)
+		}
+		return nil
+	}
+	if s.Topology != nil && s.Topology.keyPath != "" {
+		if _, err := os.Stat(s.Topology.keyPath); err == nil {
+			s.KeyPath = s.Topology.keyPath
+			return nil
+		}
+	}
 	tiupRoot, err := s.WhereTiup()
 	if err != nil {
-		return err
+		if s.Agent || s.Password != "" {
+			return nil
+		}
+		return fmt.Errorf("no ssh key, set ssh.key, ssh.agent, ssh.password or install tiup: %w", err)
 	}
-	s.sshKey.privateKey = privateKeyPath(tiupRoot, s.Cluster.Name)
-	s.sshKey.publicKey = publicKeyPath(tiupRoot, S.Cluster.Name)
+	s.KeyPath = privateKeyPath(tiupRoot, s.Cluster.Name)
 	return nil
 }
 
@@ -24,18 +40,6 @@ func privateKeyPath(root, clusterName string) string {
 	return path.Join(root, "storage", "cluster", "clusters", clusterName, "ssh", "id_rsa")
 }
 
-func publicKeyPath(root, clusterName string) string {
-	return path.Join(root, "storage", "cluster", "clusters", clusterName, "ssh", "id_rsa.pub")
-}
-
-func (s *SSH) ParsePrivateKey() (ssh.Signer, error) {
-	file, err := ioutil.ReadFile(s.sshKey.privateKey)
-	if err != nil {
-		return nil, err
-	}
-	return ssh.ParsePrivateKey(file)
-}
-
 func (s *SSH) WhereTiup() (string, error) {
 	up, err := exec.LookPath("tiup")
 	if err != nil {
@@ -45,13 +49,15 @@ func (s *SSH) WhereTiup() (string, error) {
 	return filepath.Join("/", base), nil
 }
 
+// CheckClusterName checks the cluster in the tiup storage, it is skipped with a topology or without tiup.
 func (s *SSH) CheckClusterName() error {
-	if !isLinux() {
+	if !isLinux() || s.Topology != nil {
 		return nil
 	}
 	tiup, err := s.WhereTiup()
 	if err != nil {
-		return err
+		log.Logger.Warnf("tiup is not found, skip checking cluster %s", s.Cluster.Name)
+		return nil
 	}
 	arg := []string{filepath.Join(tiup, "storage", "cluster", "clusters")}
 	o, err := s.RunLocalWithArg("ls", arg)
@@ -77,6 +83,11 @@ func (s *SSH) ScaleIn(addr string) ([]byte, error) {
 	return s.RunLocal(c)
 }
 
+func (s *SSH) ScaleOut(topology string) ([]byte, error) {
+	c := fmt.Sprintf("tiup cluster scale-out %s %s -u %s --yes", s.Cluster.Name, topology, s.credential("").User)
+	return s.RunLocal(c)
+}
+
 const noToAlways = "sudo sed -i 's/no/always/g' %s"
 const alwaysToNo = "sudo sed -i 's/always/no/g' %s"
 const reloadSystemd = "sudo systemctl daemon-reload"
@@ -85,6 +96,17 @@ const (
 	No
 )
 
+const (
+	Start = "start"
+	Stop  = "stop"
+)
+
+// Systemctl starts or stops the service, the service is the path of the unit file or its name.
+func (s *SSH) Systemctl(host, action, service string) ([]byte, error) {
+	c := fmt.Sprintf("sudo systemctl %s %s", action, path.Base(service))
+	return s.RunSSH(host, c)
+}
+
 func (s *SSH) Systemd(host string, w int, f string) ([]byte, error) {
 	var cmd string
 	switch w {
@@ -0,0 +1,205 @@
+package ssh
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+)
+
+// Instance is a component instance of the topology, role is tidb, tikv, pd, tiflash, grafana or prometheus.
+type Instance struct {
+	Role      string
+	Host      string
+	Port      int
+	SshPort   int
+	DeployDir string
+	LogDir    string
+	DataDir   string
+}
+
+// Topology is read from the meta.yaml of a tiup cluster or an inventory file in the format of the tiup topology,
+// so tipoc runs on a host without tiup.
+type Topology struct {
+	User      string
+	SshPort   int
+	Instances []Instance
+	// keyPath is the ssh key kept next to meta.yaml by tiup, e.g. clusters/<name>/ssh/id_rsa.
+	keyPath string
+}
+
+type topologyGlobal struct {
+	User      string `yaml:"user"`
+	SshPort   int    `yaml:"ssh_port"`
+	DeployDir string `yaml:"deploy_dir"`
+	DataDir   string `yaml:"data_dir"`
+	LogDir    string `yaml:"log_dir"`
+}
+
+type topologyInstance struct {
+	Host       string `yaml:"host"`
+	SshPort    int    `yaml:"ssh_port"`
+	Port       int    `yaml:"port"`
+	ClientPort int    `yaml:"client_port"`
+	TCPPort    int    `yaml:"tcp_port"`
+	DeployDir  string `yaml:"deploy_dir"`
+	DataDir    string `yaml:"data_dir"`
+	LogDir     string `yaml:"log_dir"`
+}
+
+type topologySpec struct {
+	Global     topologyGlobal     `yaml:"global"`
+	TiDB       []topologyInstance `yaml:"tidb_servers"`
+	TiKV       []topologyInstance `yaml:"tikv_servers"`
+	PD         []topologyInstance `yaml:"pd_servers"`
+	TiFlash    []topologyInstance `yaml:"tiflash_servers"`
+	Grafana    []topologyInstance `yaml:"grafana_servers"`
+	Prometheus []topologyInstance `yaml:"monitoring_servers"`
+}
+
+// topologyMeta is meta.yaml, the inventory file is the topology alone.
+type topologyMeta struct {
+	User     string        `yaml:"user"`
+	Topology *topologySpec `yaml:"topology"`
+}
+
+// the default ports of the roles, the same as tiup.
+var defaultPorts = map[string]int{
+	"tidb":       4000,
+	"tikv":       20160,
+	"pd":         2379,
+	"tiflash":    9000,
+	"grafana":    3000,
+	"prometheus": 9090,
+}
+
+// LoadTopology reads meta.yaml or an inventory file, the dirs of the instances are completed like tiup.
+func LoadTopology(path string) (*Topology, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var meta topologyMeta
+	if err := yaml.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("parse topology %s failed: %w", path, err)
+	}
+	spec := meta.Topology
+	t := &Topology{User: meta.User}
+	if spec == nil {
+		spec = &topologySpec{}
+		if err := yaml.Unmarshal(b, spec); err != nil {
+			return nil, fmt.Errorf("parse topology %s failed: %w", path, err)
+		}
+	} else {
+		t.keyPath = filepath.Join(filepath.Dir(path), "ssh", "id_rsa")
+	}
+	g := spec.Global
+	if g.User != "" {
+		t.User = g.User
+	}
+	if t.User == "" {
+		t.User = "tidb"
+	}
+	t.SshPort = g.SshPort
+	if t.SshPort == 0 {
+		t.SshPort = 22
+	}
+	if g.DeployDir == "" {
+		g.DeployDir = "deploy"
+	}
+	if g.DataDir == "" {
+		g.DataDir = "data"
+	}
+	g.DeployDir = t.abs(g.DeployDir)
+	for _, r := range []struct {
+		role      string
+		instances []topologyInstance
+	}{
+		{"tidb", spec.TiDB},
+		{"tikv", spec.TiKV},
+		{"pd", spec.PD},
+		{"tiflash", spec.TiFlash},
+		{"grafana", spec.Grafana},
+		{"prometheus", spec.Prometheus},
+	} {
+		for _, in := range r.instances {
+			if in.Host == "" {
+				return nil, fmt.Errorf("topology %s: a host of %s_servers is empty", path, r.role)
+			}
+			i := Instance{
+				Role:    r.role,
+				Host:    in.Host,
+				Port:    in.Port,
+				SshPort: in.SshPort,
+			}
+			switch {
+			case r.role == "pd" && in.ClientPort != 0:
+				i.Port = in.ClientPort
+			case r.role == "tiflash" && in.TCPPort != 0:
+				i.Port = in.TCPPort
+			}
+			if i.Port == 0 {
+				i.Port = defaultPorts[r.role]
+			}
+			if i.SshPort == 0 {
+				i.SshPort = t.SshPort
+			}
+			dir := fmt.Sprintf("%s-%d", r.role, i.Port)
+			i.DeployDir = in.DeployDir
+			if i.DeployDir == "" {
+				i.DeployDir = filepath.Join(g.DeployDir, dir)
+			}
+			i.DeployDir = t.abs(i.DeployDir)
+			i.LogDir = t.instanceDir(i.DeployDir, in.LogDir, g.LogDir, "log")
+			i.DataDir = t.instanceDir(i.DeployDir, in.DataDir, filepath.Join(g.DataDir, dir), "data")
+			t.Instances = append(t.Instances, i)
+		}
+	}
+	if len(t.Instances) == 0 {
+		return nil, fmt.Errorf("topology %s has no instance", path)
+	}
+	return t, nil
+}
+
+// abs is the dir under the home of the user if it is relative, the same as tiup.
+func (t *Topology) abs(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join("/home", t.User, dir)
+}
+
+// instanceDir is the dir of the instance, the global one or the default one under the deploy dir, in order.
+func (t *Topology) instanceDir(deployDir, dir, global, def string) string {
+	switch {
+	case dir != "" && filepath.IsAbs(dir):
+		return dir
+	case dir != "":
+		return filepath.Join(deployDir, dir)
+	case global != "" && filepath.IsAbs(global):
+		return global
+	default:
+		return filepath.Join(deployDir, def)
+	}
+}
+
+// sshPort is the ssh port of the host, false if the host is not in the topology.
+func (t *Topology) sshPort(host string) (string, bool) {
+	for _, i := range t.Instances {
+		if i.Host == host {
+			return strconv.Itoa(i.SshPort), true
+		}
+	}
+	return "", false
+}
+
+// Find is the instance of the role on the host and the port.
+func (t *Topology) Find(role, host string, port int) (Instance, bool) {
+	for _, i := range t.Instances {
+		if i.Role == role && i.Host == host && i.Port == port {
+			return i, true
+		}
+	}
+	return Instance{}, false
+}
@@ -0,0 +1,234 @@
+package chart
+
+import (
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"time"
+)
+
+// Series is one line of the chart.
+type Series struct {
+	Name   string
+	Times  []time.Time
+	Values []float64
+}
+
+const (
+	width      = 1000
+	height     = 500
+	marginLeft = 80
+	marginTop  = 30
+	marginEnd  = 20
+	legendLine = 15
+	grids      = 5
+	// maxLegend is the number of series named in the legend, the rest are drawn without names.
+	maxLegend = 8
+)
+
+var (
+	background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	foreground = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	gridColor  = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+	palette    = []color.RGBA{
+		{R: 0x73, G: 0xbf, B: 0x69, A: 0xff},
+		{R: 0xf2, G: 0xcc, B: 0x0c, A: 0xff},
+		{R: 0x56, G: 0x94, B: 0xf2, A: 0xff},
+		{R: 0xff, G: 0x78, B: 0x0a, A: 0xff},
+		{R: 0xf2, G: 0x49, B: 0x5c, A: 0xff},
+		{R: 0x5a, G: 0xc8, B: 0xfa, A: 0xff},
+		{R: 0xb8, G: 0x77, B: 0xd9, A: 0xff},
+		{R: 0x70, G: 0x5d, B: 0xa0, A: 0xff},
+	}
+)
+
+// SavePNG draws the series as a line chart, the time axis is shared by all series.
+func SavePNG(name, title string, series []Series) error {
+	img := Line(title, series)
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// Line draws the series, the legend names the first maxLegend series.
+func Line(title string, series []Series) *image.RGBA {
+	legends := len(series)
+	if legends > maxLegend {
+		legends = maxLegend
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height+legends*legendLine))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	drawText(img, marginLeft, 18, title, foreground)
+
+	minT, maxT, minV, maxV, ok := bounds(series)
+	plot := image.Rect(marginLeft, marginTop, width-marginEnd, height-40)
+	if !ok {
+		drawText(img, plot.Min.X+plot.Dx()/2-20, plot.Min.Y+plot.Dy()/2, "no data", foreground)
+		return img
+	}
+
+	// horizontal grid with the value labels
+	for i := 0; i <= grids; i++ {
+		y := plot.Max.Y - i*plot.Dy()/grids
+		drawHLine(img, plot.Min.X, plot.Max.X, y, gridColor)
+		v := minV + (maxV-minV)*float64(i)/grids
+		label := formatValue(v)
+		drawText(img, plot.Min.X-8-len(label)*7, y+4, label, foreground)
+	}
+	drawVLine(img, plot.Min.X, plot.Min.Y, plot.Max.Y, foreground)
+	drawHLine(img, plot.Min.X, plot.Max.X, plot.Max.Y, foreground)
+	drawText(img, plot.Min.X, plot.Max.Y+16, minT.Format("15:04:05"), foreground)
+	end := maxT.Format("15:04:05")
+	drawText(img, plot.Max.X-len(end)*7, plot.Max.Y+16, end, foreground)
+
+	x := func(t time.Time) int {
+		span := maxT.Sub(minT)
+		if span == 0 {
+			return plot.Min.X
+		}
+		return plot.Min.X + int(float64(plot.Dx())*float64(t.Sub(minT))/float64(span))
+	}
+	y := func(v float64) int {
+		return plot.Max.Y - int(float64(plot.Dy())*(v-minV)/(maxV-minV))
+	}
+	for i, s := range series {
+		c := palette[i%len(palette)]
+		for k := 1; k < len(s.Values); k++ {
+			if math.IsNaN(s.Values[k-1]) || math.IsNaN(s.Values[k]) {
+				continue
+			}
+			drawLine(img, x(s.Times[k-1]), y(s.Values[k-1]), x(s.Times[k]), y(s.Values[k]), c)
+		}
+		if i < maxLegend {
+			ly := plot.Max.Y + 32 + i*legendLine
+			draw.Draw(img, image.Rect(plot.Min.X, ly-8, plot.Min.X+16, ly-2), &image.Uniform{C: c}, image.Point{}, draw.Src)
+			drawText(img, plot.Min.X+22, ly, s.Name, foreground)
+		}
+	}
+	return img
+}
+
+func bounds(series []Series) (minT, maxT time.Time, minV, maxV float64, ok bool) {
+	minV, maxV = math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for i, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			t := s.Times[i]
+			if !ok || t.Before(minT) {
+				minT = t
+			}
+			if !ok || t.After(maxT) {
+				maxT = t
+			}
+			ok = true
+			minV = math.Min(minV, v)
+			maxV = math.Max(maxV, v)
+		}
+	}
+	if !ok {
+		return
+	}
+	// start from zero for the counters, keep a flat line in the middle
+	if minV > 0 {
+		minV = 0
+	}
+	if maxV == minV {
+		maxV = minV + 1
+	}
+	maxV = minV + niceStep((maxV-minV)/grids)*grids
+	return
+}
+
+// niceStep rounds the step up to 1, 2 or 5 times a power of 10, so the labels of the grid are readable.
+func niceStep(step float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if step <= m*p {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+func formatValue(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return fmt.Sprintf("%.1fG", v/1e9)
+	case abs >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case abs >= 1e3:
+		return fmt.Sprintf("%.1fK", v/1e3)
+	default:
+		return fmt.Sprintf("%.4g", v)
+	}
+}
+
+func drawText(img *image.RGBA, x, y int, s string, c color.RGBA) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func drawHLine(img *image.RGBA, x1, x2, y int, c color.RGBA) {
+	for x := x1; x <= x2; x++ {
+		img.Set(x, y, c)
+	}
+}
+
+func drawVLine(img *image.RGBA, x, y1, y2 int, c color.RGBA) {
+	for y := y1; y <= y2; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// drawLine is Bresenham's line algorithm.
+func drawLine(img *image.RGBA, x1, y1, x2, y2 int, c color.RGBA) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.Set(x1, y1, c)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
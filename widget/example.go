@@ -2,16 +2,19 @@ package widget
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"github.com/gizak/termui/v3/widgets"
+	"io/fs"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"pictorial/comp"
 	"pictorial/operator"
 	"strings"
 )
 
-//go:embed "script/*.sql"
+//go:embed "script"
 var scriptPath embed.FS
 
 type Example struct {
@@ -21,14 +24,17 @@ type Example struct {
 }
 
 var OTypeCompMapping = map[string]operator.OType{
-	"7.1": operator.RecoverSystemd,
-	"7.2": operator.Kill,
-	"7.3": operator.DataCorrupted,
-	"7.4": operator.Crash,
-	"7.5": operator.Disaster,
-	"7.6": operator.Reboot,
-	"7.7": operator.DiskFull,
-	"9.2": operator.ScaleIn,
+	"7.1":  operator.RecoverSystemd,
+	"7.2":  operator.Kill,
+	"7.3":  operator.DataCorrupted,
+	"7.4":  operator.Crash,
+	"7.5":  operator.Disaster,
+	"7.6":  operator.Reboot,
+	"7.7":  operator.DiskFull,
+	"7.8":  operator.NetworkIsolation,
+	"7.9":  operator.NetworkDelay,
+	"7.10": operator.NetworkPartition,
+	"9.2":  operator.ScaleIn,
 }
 
 func IsCompCatalogMapping(idx string) bool {
@@ -50,18 +56,14 @@ func NewExample(v string, c comp.CType, o operator.OType) *Example {
 	}
 }
 
-func getIdxByValue(v string) string {
+func GetIdxByValue(v string) string {
 	return strings.Split(v, " ")[0]
 }
 
-func getNameByValue(v string) string {
+func GetNameByValue(v string) string {
 	return strings.Split(v, " ")[1]
 }
 
-func (e Example) isConflict(o operator.OType) bool {
-	return e.OType != o
-}
-
 func ChangeToExample(node *widgets.TreeNode) *Example {
 	return node.Value.(*Example)
 }
@@ -113,8 +115,49 @@ func (e Example) scriptIsNotExists() error {
 
 const tableNameIdentification = "${TABLE_NAME}"
 
+func (e Example) tableName() string {
+	return fmt.Sprintf("%s.%s", "poc", strings.Split(e.Value, " ")[1])
+}
+
 func (e Example) replaceTableName(o []byte) string {
-	tableName := strings.Split(e.Value, " ")[1]
-	tableName = fmt.Sprintf("%s.%s", "poc", tableName)
-	return strings.ReplaceAll(string(o), tableNameIdentification, tableName)
+	return strings.ReplaceAll(string(o), tableNameIdentification, e.tableName())
+}
+
+// ExpectedSuffix is the suffix of the golden output of a transcript.
+const ExpectedSuffix = ".result"
+
+// Expected reads the golden output of a transcript, e.g. "script/1.2.1.1 select_table.result",
+// other scripts keep it next to themselves. It returns nil if there is no golden output.
+func (e Example) Expected(transcript string) ([]byte, error) {
+	var output []byte
+	var err error
+	switch e.OType {
+	case operator.Script, operator.SafetyScript:
+		output, err = scriptPath.ReadFile(fmt.Sprintf("script/%s%s", transcript, ExpectedSuffix))
+	case operator.OtherScript:
+		output, err = ioutil.ReadFile(filepath.Join(OtherConfig, transcript+ExpectedSuffix))
+	default:
+		return nil, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.OType != operator.OtherScript {
+		return []byte(e.replaceTableName(output)), nil
+	}
+	return output, nil
+}
+
+// Record writes the transcript into dir as its golden output, the table name of a built-in script is put back
+// to ${TABLE_NAME}, so the file is copied next to the script as it is.
+func (e Example) Record(dir, transcript string, output []string) (string, error) {
+	o := strings.Join(output, "\n") + "\n"
+	if e.OType != operator.OtherScript {
+		o = strings.ReplaceAll(o, e.tableName(), tableNameIdentification)
+	}
+	fName := filepath.Join(dir, transcript+ExpectedSuffix)
+	return fName, os.WriteFile(fName, []byte(o), 0644)
 }
@@ -0,0 +1,149 @@
+package widget
+
+import (
+	"fmt"
+	"github.com/gizak/termui/v3/widgets"
+	"path"
+	"pictorial/comp"
+	"pictorial/log"
+	"pictorial/operator"
+	"strings"
+)
+
+const (
+	caseSeparator   = ","
+	targetSeparator = ":"
+	pathSeparator   = "/"
+)
+
+// Select resolves a case expression such as "1.2.*,2.3,7.2:tikv/10.0.0.1:20160"
+// against the candidate tree and returns a selected tree, the same one built by arrow keys.
+func Select(t *widgets.Tree, cases string) (*widgets.Tree, error) {
+	var chosen []*Example
+	visited := make(map[*widgets.TreeNode]bool)
+	for _, c := range strings.Split(cases, caseSeparator) {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		pattern, target := c, ""
+		if i := strings.Index(c, targetSeparator); i != -1 {
+			pattern, target = c[:i], c[i+1:]
+		}
+		nodes, err := selectNodes(t, pattern, target)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("case [%s] matches nothing", c)
+		}
+		for _, node := range nodes {
+			if visited[node] {
+				continue
+			}
+			visited[node] = true
+			chosen = append(chosen, ChangeToExample(node))
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, fmt.Errorf("no case selected")
+	}
+	s := widgets.NewTree()
+	var nodes []*widgets.TreeNode
+	for _, e := range chosen {
+		nodes = append(nodes, &widgets.TreeNode{
+			Value: NewExample(e.Value, e.CType, e.OType),
+		})
+	}
+	s.SetNodes(nodes)
+	s.Title = SelectedTitle(s)
+	return s, nil
+}
+
+func selectNodes(t *widgets.Tree, pattern, target string) ([]*widgets.TreeNode, error) {
+	var nodes []*widgets.TreeNode
+	var err error
+	t.Walk(func(node *widgets.TreeNode) bool {
+		if !matchNode(node, pattern) {
+			return true
+		}
+		idx := GetIdxByValue(node.Value.String())
+		switch {
+		case IsCompCatalogMapping(idx):
+			if target == "" {
+				err = fmt.Errorf("[%s] needs a target, e.g. %s:tikv/127.0.0.1:20160", idx, idx)
+				return false
+			}
+			nodes = append(nodes, selectTarget(node, OTypeCompMapping[idx], target)...)
+		case target != "":
+			err = fmt.Errorf("[%s] does not accept a target: %s", idx, target)
+			return false
+		default:
+			nodes = append(nodes, leaves(node)...)
+		}
+		return true
+	})
+	return nodes, err
+}
+
+func matchNode(node *widgets.TreeNode, pattern string) bool {
+	v := node.Value.String()
+	if v == OtherConfig {
+		return pattern == v
+	}
+	if ok, _ := path.Match(pattern, GetIdxByValue(v)); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, v)
+	return ok
+}
+
+// selectTarget matches "<ctype or label>/<address or label value>" under a component catalog,
+// a symbolic target such as pd-leader or region-leader:poc.t1 may omit the ctype and is resolved when the job runs.
+func selectTarget(node *widgets.TreeNode, o operator.OType, target string) []*widgets.TreeNode {
+	group, value := target, "*"
+	if i := strings.Index(target, pathSeparator); i != -1 {
+		group, value = target[:i], target[i+1:]
+	} else if cType, ok := comp.SymbolCType(target); ok {
+		group, value = comp.GetCTypeValue(cType), target
+	}
+	var nodes []*widgets.TreeNode
+	for _, g := range node.Nodes {
+		if ok, _ := path.Match(group, g.Value.String()); !ok {
+			continue
+		}
+		for _, e := range g.Nodes {
+			v := e.Value.String()
+			if ok, _ := path.Match(value, v); ok {
+				nodes = append(nodes, e)
+			} else if ok, _ := path.Match(value, comp.CleanLeaderFlag(v)); ok {
+				nodes = append(nodes, e)
+			}
+		}
+	}
+	// the symbol with an argument is not listed in the tree
+	if cType, ok := comp.SymbolCType(value); ok && len(nodes) == 0 && group == comp.GetCTypeValue(cType) {
+		for _, g := range node.Nodes {
+			if g.Value.String() == group {
+				nodes = append(nodes, &widgets.TreeNode{Value: NewExample(value, cType, o)})
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+func leaves(node *widgets.TreeNode) []*widgets.TreeNode {
+	if len(node.Nodes) == 0 {
+		if _, ok := node.Value.(*Example); ok {
+			return []*widgets.TreeNode{node}
+		}
+		log.Logger.Debugf("%s is an empty catalog, skip", node.Value.String())
+		return nil
+	}
+	var nodes []*widgets.TreeNode
+	for _, n := range node.Nodes {
+		nodes = append(nodes, leaves(n)...)
+	}
+	return nodes
+}
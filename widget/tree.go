@@ -89,7 +89,7 @@ func appendOther(treeNodes *[]*widgets.TreeNode) error {
 				log.Logger.Warnf("otherConfig %s is empty or not exists, skip", path)
 				return nil
 			}
-			if !info.IsDir() {
+			if !info.IsDir() && !strings.HasSuffix(info.Name(), ExpectedSuffix) {
 				e := NewExample(info.Name(), -1, operator.OtherScript)
 				appendExampleNode(&othersNode, e)
 			}
@@ -111,11 +111,16 @@ func GetJobMapping() map[string]operator.OType {
 		"3.6":    operator.AddIndexPerformance,
 		"3.7":    operator.OnlineDDLModifyColumn,
 		"4.5":    operator.GeneralLog,
+		"5.2":    operator.HtapWorkload,
 		"6":      operator.SafetyScript,
 		"8.1":    operator.LoadDataTPCC,
 		"8.2":    operator.LoadDataImportInto,
 		"8.3":    operator.LoadData,
 		"8.4":    operator.LoadDataSelectIntoOutFile,
+		"9.1.1":  operator.ScaleOut,
+		"9.1.2":  operator.ScaleOut,
+		"9.1.3":  operator.ScaleOut,
+		"9.1.4":  operator.ScaleOut,
 		"20.1":   operator.InstallSysBench,
 	}
 }
@@ -129,7 +134,7 @@ func walkTree(tree *widgets.Tree) {
 		if len(node.Nodes) != 0 {
 			node.Value = newCatalog(v)
 		} else {
-			idx := getIdxByValue(v)
+			idx := GetIdxByValue(v)
 			if IsCompCatalogMapping(idx) {
 				node.Value = newCatalog(v)
 			} else {
@@ -155,11 +160,11 @@ func appendComponent(tree *widgets.Tree) error {
 	tree.Walk(func(node *widgets.TreeNode) bool {
 		switch node.Value.(type) {
 		case *Catalog:
-			idx := getIdxByValue(node.Value.String())
+			idx := GetIdxByValue(node.Value.String())
 			if IsCompCatalogMapping(idx) {
 				oTp = OTypeCompMapping[idx]
 				switch oTp {
-				case operator.Disaster:
+				case operator.Disaster, operator.NetworkPartition:
 					appendLabelNode(node, cs.Map)
 				case operator.DataCorrupted, operator.DiskFull:
 					appendComponentNode(node, cs.Map, []comp.CType{comp.TiKV, comp.PD})
@@ -185,6 +190,9 @@ func appendComponentNode(node *widgets.TreeNode, m map[comp.CType][]comp.Compone
 				e := NewExample(addr, cType, oTp)
 				appendExampleNode(cNode, e)
 			}
+			for _, symbol := range comp.Symbols(cType, m[cType]) {
+				appendExampleNode(cNode, NewExample(symbol, cType, oTp))
+			}
 		}
 	}
 }
@@ -201,7 +209,7 @@ func appendLabelNode(node *widgets.TreeNode, m map[comp.CType][]comp.Component)
 				continue
 			}
 			visited[value] = true
-			e := NewExample(value, comp.TiKV, operator.Disaster)
+			e := NewExample(value, comp.TiKV, oTp)
 			appendExampleNode(cNode, e)
 		}
 	}
@@ -255,22 +263,39 @@ func TreeLength(tree *widgets.Tree) int {
 	return length
 }
 
-func contains(tree *widgets.Tree, value string) bool {
-	arr := treeToArray(tree)
-	set := make(map[string]bool)
-	for _, v := range arr {
-		set[v] = true
-	}
-	return set[value]
+// containsExample reports whether the tree has the same example, the same value of different OTypes is not duplicate.
+func containsExample(tree *widgets.Tree, e *Example) bool {
+	found := false
+	tree.Walk(func(node *widgets.TreeNode) bool {
+		if s, ok := node.Value.(*Example); ok && s.Value == e.Value && s.OType == e.OType {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
 }
 
-func treeToArray(tree *widgets.Tree) []string {
-	var array []string
-	tree.Walk(func(treeNode *widgets.TreeNode) bool {
-		array = append(array, treeNode.Value.String())
+// Mixed is the title of the selected tree which has more than one OType.
+const Mixed = "mixed"
+
+// SelectedTitle is the OType of the selected examples, or Mixed.
+func SelectedTitle(tree *widgets.Tree) string {
+	title := ""
+	tree.Walk(func(node *widgets.TreeNode) bool {
+		e, ok := node.Value.(*Example)
+		if !ok {
+			return true
+		}
+		v := operator.GetOTypeValue(e.OType)
+		if title != "" && title != v {
+			title = Mixed
+			return false
+		}
+		title = v
 		return true
 	})
-	return array
+	return title
 }
 
 func CleanTree(tree *widgets.Tree) {
@@ -1,18 +1,23 @@
 package widget
 
 import (
+	"fmt"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
+	"pictorial/bench"
 	"pictorial/log"
 	"pictorial/operator"
 )
 
 type Widget struct {
-	T *widgets.Tree
-	S *widgets.Tree
-	O *widgets.List
-	P *widgets.Gauge
-	L *widgets.List
+	T  *widgets.Tree
+	S  *widgets.Tree
+	O  *widgets.List
+	P  *widgets.Gauge
+	L  *widgets.List
+	LP *widgets.Plot
+	// lines are the indexes of the workloads in the data of LP.
+	lines map[string]int
 }
 
 const (
@@ -97,7 +102,7 @@ func NewLoad() *widgets.List {
 	l.Title = Load
 	l.WrapText = false
 	l.TitleStyle = ui.NewStyle(ui.ColorClear)
-	l.SetRect(2*x/3, 0, x, y-1)
+	l.SetRect(2*x/3, y/3, x, y-1)
 	l.Block.BorderStyle = ui.NewStyle(ui.ColorClear)
 	l.SelectedRowStyle = ui.NewStyle(ui.ColorClear)
 	l.TextStyle = ui.Style{
@@ -107,6 +112,21 @@ func NewLoad() *widgets.List {
 	return l
 }
 
+// NewLoadPlot shows the tps of the load above the other lines of the load log.
+func NewLoadPlot() *widgets.Plot {
+	x, y := ui.TerminalDimensions()
+	p := widgets.NewPlot()
+	p.Title = Load
+	p.TitleStyle = ui.NewStyle(ui.ColorClear)
+	p.SetRect(2*x/3, 0, x, y/3)
+	p.Block.BorderStyle = ui.NewStyle(ui.ColorClear)
+	p.AxesColor = ui.ColorWhite
+	p.LineColors = []ui.Color{ui.ColorGreen, ui.ColorCyan, ui.ColorYellow, ui.ColorMagenta, ui.ColorBlue}
+	// an empty plot is drawn with the axes only
+	p.MaxVal = 1
+	return p
+}
+
 func NewProcessBar() *widgets.Gauge {
 	x, y := ui.TerminalDimensions()
 	p := widgets.NewGauge()
@@ -124,22 +144,8 @@ func (w *Widget) ScrollRight() {
 	case *Example:
 		e := ChangeToExample(w.T.SelectedNode())
 		if len(w.T.SelectedNode().Nodes) == 0 {
-			conflictOrDuplicate := false
-			w.S.Walk(func(node *widgets.TreeNode) bool {
-				targetNode := ChangeToExample(node)
-				if e.isConflict(targetNode.OType) {
-					conflictOrDuplicate = true
-					log.Logger.Warnf("conflict catalog: %s - %s", operator.GetOTypeValue(e.OType), operator.GetOTypeValue(node.Value.(*Example).OType))
-					return false
-				}
-				if contains(w.S, e.Value) {
-					conflictOrDuplicate = true
-					log.Logger.Warnf("duplicate: [%s] %s ", operator.GetOTypeValue(e.OType), e.Value)
-					return false
-				}
-				return true
-			})
-			if conflictOrDuplicate {
+			if containsExample(w.S, e) {
+				log.Logger.Warnf("duplicate: [%s] %s ", operator.GetOTypeValue(e.OType), e.Value)
 				return
 			}
 			newNode := widgets.TreeNode{
@@ -153,7 +159,7 @@ func (w *Widget) ScrollRight() {
 			newChosen = append(newChosen, &newNode)
 			w.S.SetNodes(newChosen)
 			w.S.ScrollBottom()
-			w.S.Title = operator.GetOTypeValue(e.OType)
+			w.S.Title = SelectedTitle(w.S)
 		}
 	case *Catalog:
 		w.T.Expand()
@@ -236,6 +242,52 @@ func (w *Widget) PrintLoad(s string) {
 	ui.Render(w.L)
 }
 
+// ResetLoadPlot clears the lines of the former job, so a job starts with an empty plot.
+func (w *Widget) ResetLoadPlot() {
+	w.lines = nil
+	w.LP.Data = [][]float64{}
+	w.LP.Title = Load
+	w.LP.MaxVal = 1
+	ui.Render(w.LP)
+}
+
+// PlotLoad appends the tps of the sample to the line of its workload, the points older than the width of the plot are dropped.
+func (w *Widget) PlotLoad(s bench.Sample) {
+	if w.lines == nil {
+		w.lines = make(map[string]int)
+	}
+	i, ok := w.lines[s.Name]
+	if !ok {
+		i = len(w.LP.Data)
+		w.lines[s.Name] = i
+		w.LP.Data = append(w.LP.Data, nil)
+	}
+	// the y axis labels take 5 columns
+	width := w.LP.Inner.Dx() - 6
+	data := append(w.LP.Data[i], s.TPS)
+	if width > 0 && len(data) > width {
+		data = data[len(data)-width:]
+	}
+	w.LP.Data[i] = data
+	w.LP.Title = fmt.Sprintf("%s %s", Load, s)
+	var max float64
+	for _, line := range w.LP.Data {
+		for _, v := range line {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	w.LP.MaxVal = max*1.1 + 1
+	// the line chart needs two points at least
+	for _, line := range w.LP.Data {
+		if len(line) < 2 {
+			return
+		}
+	}
+	ui.Render(w.LP)
+}
+
 func splitByX(s string, x int) []string {
 	var result []string
 	for i := 0; i < len(s); i += x {